@@ -21,10 +21,14 @@ import (
 
 // ConfigCreds are the credentials for a given notehub
 type ConfigCreds struct {
-	User  string `json:"user,omitempty"`
-	Token string `json:"token,omitempty"`
+	User    string `json:"user,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Expires int64  `json:"expires,omitempty"`
 }
 
+// ConfigCredsExpiryWarning is how long before a token expires that we begin to warn about it
+const ConfigCredsExpiryWarning = 5 * time.Minute
+
 // Port/PortConfig on a per-interface basis
 type ConfigPort struct {
 	Port       string `json:"port,omitempty"`
@@ -40,12 +44,22 @@ type ConfigSettings struct {
 	IPort     map[string]ConfigPort  `json:"iport,omitempty"`
 }
 
+// ConfigInterfaceLease is the interface used to reach a leased, networked notecard.  The
+// port is the scope of notecards that may be leased, and the port config is the lease
+// duration in minutes.
+const ConfigInterfaceLease = "lease"
+
 // Config are the master config settings
 var Config ConfigSettings
 var configFlagHub string
 var configFlagInterface string
 var configFlagPort string
 var configFlagPortConfig int
+var configFlagLease string
+var configFlagLeaseMins int
+var configFlagNoSave bool
+var configFlagProfile string
+var configExpiryWarned bool
 
 // ConfigRead reads the current info from config file
 func ConfigRead() error {
@@ -73,9 +87,24 @@ func ConfigRead() error {
 
 }
 
+// ConfigNoSave returns true if config writes are disabled for this invocation, either by
+// the -no-save flag or by the NOTE_NO_SAVE environment variable
+func ConfigNoSave() bool {
+	if configFlagNoSave {
+		return true
+	}
+	noSave, _ := strconv.ParseBool(os.Getenv("NOTE_NO_SAVE"))
+	return noSave
+}
+
 // ConfigWrite updates the file with the current config info
 func ConfigWrite() error {
 
+	// Leave the file untouched if saving is disabled
+	if ConfigNoSave() {
+		return nil
+	}
+
 	// Marshal it
 	configJSON, _ := note.JSONMarshalIndent(Config, "", "    ")
 
@@ -120,13 +149,19 @@ func ConfigShow() error {
 	if Config.HubCreds == nil {
 		Config.HubCreds = map[string]ConfigCreds{}
 	}
+	if profile := ConfigProfile(); profile != "" {
+		fmt.Printf("   -profile %s\n", profile)
+	}
 	if len(Config.HubCreds) != 0 {
 		fmt.Printf("     creds:\n")
 		for hub, cred := range Config.HubCreds {
 			fmt.Printf("            %s: %s\n", hub, cred.User)
 		}
 	}
-	if Config.Interface != "" {
+	if Config.Interface == ConfigInterfaceLease {
+		fmt.Printf("   -lease %s\n", Config.IPort[Config.Interface].Port)
+		fmt.Printf("   -lease-mins %d\n", Config.IPort[Config.Interface].PortConfig)
+	} else if Config.Interface != "" {
 		fmt.Printf("   -interface %s\n", Config.Interface)
 		if Config.IPort[Config.Interface].Port == "" {
 			fmt.Printf("   -port -\n")
@@ -187,6 +222,15 @@ func ConfigFlagsProcess() (err error) {
 		temp.PortConfig = configFlagPortConfig
 		Config.IPort[Config.Interface] = temp
 	}
+	if configFlagLease != "" {
+		Config.Interface = ConfigInterfaceLease
+		temp := Config.IPort[Config.Interface]
+		temp.Port = configFlagLease
+		if configFlagLeaseMins != 0 {
+			temp.PortConfig = configFlagLeaseMins
+		}
+		Config.IPort[Config.Interface] = temp
+	}
 	if Config.Interface == "" {
 		configFlagPort = ""
 		configFlagPortConfig = 0
@@ -202,13 +246,17 @@ func ConfigFlagsRegister(notecardFlags bool, notehubFlags bool) {
 
 	// Process the commands
 	if notecardFlags {
-		flag.StringVar(&configFlagInterface, "interface", "", "select 'serial' or 'i2c' interface for notecard")
+		flag.StringVar(&configFlagInterface, "interface", "", "select 'serial', 'i2c', or 'lease' interface for notecard")
 		flag.StringVar(&configFlagPort, "port", "", "select serial or i2c port for notecard")
-		flag.IntVar(&configFlagPortConfig, "portconfig", 0, "set serial device speed or i2c address for notecard")
+		flag.IntVar(&configFlagPortConfig, "portconfig", 0, "set serial device speed, i2c address, or lease minutes for notecard")
+		flag.StringVar(&configFlagLease, "lease", "", "for this command only, lease a networked notecard from the specified scope")
+		flag.IntVar(&configFlagLeaseMins, "lease-mins", 0, "with -lease, the number of minutes for which to hold the lease")
 	}
 	if notehubFlags {
 		flag.StringVar(&configFlagHub, "hub", "", "set notehub domain")
+		flag.StringVar(&configFlagProfile, "profile", "", "use the credentials of this named profile (default NOTEHUB_PROFILE, or the hub's default profile)")
 	}
+	flag.BoolVar(&configFlagNoSave, "no-save", false, "apply config flags to this command only, never writing them to the config file")
 
 }
 
@@ -247,7 +295,7 @@ func FlagParse(notecardFlags bool, notehubFlags bool) (err error) {
 			}
 		}
 	}
-	if configOnly && Config.Interface != "lease" {
+	if configOnly && Config.Interface != ConfigInterfaceLease && !ConfigNoSave() {
 		fmt.Printf("*** saving configuration ***")
 		ConfigWrite()
 		ConfigShow()
@@ -280,6 +328,34 @@ func FlagParse(notecardFlags bool, notehubFlags bool) (err error) {
 
 }
 
+// ConfigProfile returns the name of the credential profile in use, from the -profile flag or
+// the NOTEHUB_PROFILE environment variable, or "" for the default profile
+func ConfigProfile() string {
+	if configFlagProfile != "" {
+		return configFlagProfile
+	}
+	return os.Getenv("NOTEHUB_PROFILE")
+}
+
+// ConfigEnvToken returns the personal access token in the NOTEHUB_TOKEN environment variable,
+// with which the CLI may sign in without user interaction, or "" if there is none
+func ConfigEnvToken() string {
+	return strings.TrimSpace(os.Getenv("NOTEHUB_TOKEN"))
+}
+
+// ConfigCredsKey returns the key under which the credentials of the configured notehub are
+// stored, which is the hub itself for the default profile, or hub/profile for a named one
+func ConfigCredsKey() (key string) {
+	key = Config.Hub
+	if key == "" {
+		key = notehub.DefaultAPIService
+	}
+	if profile := ConfigProfile(); profile != "" {
+		key += "/" + profile
+	}
+	return
+}
+
 // ConfigSignedIn returns info about whether or not we're signed in
 func ConfigSignedIn() (username string, token string, authenticated bool) {
 	if Config.IPort == nil {
@@ -288,11 +364,7 @@ func ConfigSignedIn() (username string, token string, authenticated bool) {
 	if Config.HubCreds == nil {
 		Config.HubCreds = map[string]ConfigCreds{}
 	}
-	hub := Config.Hub
-	if hub == "" {
-		hub = notehub.DefaultAPIService
-	}
-	creds, present := Config.HubCreds[hub]
+	creds, present := Config.HubCreds[ConfigCredsKey()]
 	if present {
 		if creds.Token != "" && creds.User != "" {
 			authenticated = true
@@ -305,6 +377,18 @@ func ConfigSignedIn() (username string, token string, authenticated bool) {
 
 }
 
+// ConfigTokenExpiring returns true if the signed-in token is known to expire soon, along with
+// how long it has left, which is negative if it has already expired
+func ConfigTokenExpiring() (expiring bool, remaining time.Duration) {
+	creds, present := Config.HubCreds[ConfigCredsKey()]
+	if !present || creds.Expires == 0 {
+		return
+	}
+	remaining = time.Until(time.Unix(creds.Expires, 0))
+	expiring = remaining < ConfigCredsExpiryWarning
+	return
+}
+
 // ConfigAuthenticationHeader sets the authorization field in the header as appropriate
 func ConfigAuthenticationHeader(httpReq *http.Request) (err error) {
 
@@ -319,6 +403,16 @@ func ConfigAuthenticationHeader(httpReq *http.Request) (err error) {
 		return
 	}
 
+	// Warn, once, if the token is about to expire so that long-running jobs aren't surprised
+	if expiring, remaining := ConfigTokenExpiring(); expiring && !configExpiryWarned {
+		configExpiryWarned = true
+		if remaining < 0 {
+			fmt.Fprintf(os.Stderr, "warning: your notehub session has expired; please use 'notehub -signin' to sign in again\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: your notehub session expires in %s; use 'notehub -signin', or -auto-reauth with NOTEHUB_TOKEN, to renew it\n", remaining.Round(time.Second))
+		}
+	}
+
 	// Set the header
 	httpReq.Header.Set("X-Session-Token", token)
 
@@ -0,0 +1,160 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blues/note-go/note"
+)
+
+// A flag that may be specified multiple times on the command line
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Comparison operators, ordered so that two-character operators are matched first
+var conditionOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// Look up a dotted field path such as "body.temp" within a JSON object
+func conditionLookup(obj map[string]interface{}, path string) (value interface{}, present bool) {
+	value = obj
+	for _, field := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, present = m[field]
+		if !present {
+			return nil, false
+		}
+	}
+	return
+}
+
+// Convert a JSON value into the string form used for comparison
+func conditionString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	valueJSON, _ := note.JSONMarshal(value)
+	return string(valueJSON)
+}
+
+// Evaluate a condition such as 'err==""' or 'body.temp>20' against a JSON response,
+// returning whether or not the condition holds and the actual value of the field
+func conditionEvaluate(obj map[string]interface{}, condition string) (result bool, actual string, err error) {
+
+	// Split the condition into field, operator, and expected value
+	op := ""
+	field := ""
+	expected := ""
+	for _, o := range conditionOperators {
+		i := strings.Index(condition, o)
+		if i > 0 {
+			op = o
+			field = strings.TrimSpace(condition[:i])
+			expected = strings.TrimSpace(condition[i+len(o):])
+			break
+		}
+	}
+	if op == "" {
+		// A bare field name is true if the field is present and not empty
+		field = strings.TrimSpace(condition)
+		value, present := conditionLookup(obj, field)
+		actual = conditionString(value)
+		result = present && actual != "" && actual != "false" && actual != "0"
+		return
+	}
+	if field == "" {
+		err = fmt.Errorf("invalid condition: %s", condition)
+		return
+	}
+
+	// Strip quotes from the expected value
+	if len(expected) >= 2 && (expected[0] == '"' || expected[0] == '\'') && expected[len(expected)-1] == expected[0] {
+		expected = expected[1 : len(expected)-1]
+	}
+
+	// Get the actual value
+	value, _ := conditionLookup(obj, field)
+	actual = conditionString(value)
+
+	// Compare numerically if both sides are numbers, else compare as strings
+	actualNum, err1 := strconv.ParseFloat(actual, 64)
+	expectedNum, err2 := strconv.ParseFloat(expected, 64)
+	cmp := strings.Compare(actual, expected)
+	if err1 == nil && err2 == nil {
+		cmp = 0
+		if actualNum < expectedNum {
+			cmp = -1
+		} else if actualNum > expectedNum {
+			cmp = 1
+		}
+	}
+
+	switch op {
+	case "==":
+		result = cmp == 0
+	case "!=":
+		result = cmp != 0
+	case ">=":
+		result = cmp >= 0
+	case "<=":
+		result = cmp <= 0
+	case ">":
+		result = cmp > 0
+	case "<":
+		result = cmp < 0
+	}
+
+	return
+
+}
+
+// Evaluate a set of assertions against a JSON response, returning an error describing all that failed
+func assertResponse(rspJSON []byte, assertions []string) (err error) {
+
+	obj := map[string]interface{}{}
+	err = note.JSONUnmarshal(rspJSON, &obj)
+	if err != nil {
+		return
+	}
+
+	failures := []string{}
+	for _, assertion := range assertions {
+		result, actual, err2 := conditionEvaluate(obj, assertion)
+		if err2 != nil {
+			return err2
+		}
+		if !result {
+			failures = append(failures, fmt.Sprintf("%s (actual: '%s')", assertion, actual))
+		}
+	}
+
+	if len(failures) > 0 {
+		err = fmt.Errorf("assertion failed: %s", strings.Join(failures, "; "))
+	}
+
+	return
+
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+)
+
+// Put the host to sleep via ATTN for the specified number of seconds, optionally having the
+// notecard hold a payload that the host can retrieve when it is awakened
+func attnSleep(seconds int, payloadFile string) (err error) {
+
+	req := map[string]interface{}{"req": "card.attn", "mode": "sleep", "seconds": seconds}
+	if payloadFile != "" {
+		var payload []byte
+		payload, err = ioutil.ReadFile(payloadFile)
+		if err != nil {
+			return
+		}
+		req["payload"] = base64.StdEncoding.EncodeToString(payload)
+	}
+
+	_, err = cardTransactionMap(req)
+	if err == nil {
+		fmt.Printf("host will be awakened via ATTN in %d seconds\n", seconds)
+	}
+	return
+
+}
+
+// Display the current ATTN configuration, and retrieve any payload stored by a prior sleep
+func attnGet(payloadFile string) (err error) {
+
+	rsp, err := cardTransactionMap(map[string]interface{}{"req": "card.attn"})
+	if err != nil {
+		return
+	}
+	if set, present := rsp["set"]; present {
+		fmt.Printf("%24s: %v\n", "ATTN Asserted", set)
+	}
+	if files, present := rsp["files"]; present {
+		fmt.Printf("%24s: %v\n", "Triggered By", files)
+	}
+	if _, present := rsp["time"]; present {
+		fmt.Printf("%24s: %s\n", "Triggered At", formatEpochTime(mapNumber(rsp, "time")))
+	}
+
+	// Retrieve the payload stored when the host was put to sleep
+	rsp, err = cardTransactionMap(map[string]interface{}{"req": "card.attn", "start": true})
+	if err != nil {
+		return
+	}
+	encoded, _ := rsp["payload"].(string)
+	if encoded == "" {
+		fmt.Printf("%24s: (none)\n", "Payload")
+		return
+	}
+	payload, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+	if payloadFile != "" {
+		err = ioutil.WriteFile(payloadFile, payload, 0644)
+		if err == nil {
+			fmt.Printf("%24s: %d bytes written to %s\n", "Payload", len(payload), payloadFile)
+		}
+		return
+	}
+	fmt.Printf("%24s: %d bytes\n%s\n", "Payload", len(payload), payload)
+
+	return
+
+}
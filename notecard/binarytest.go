@@ -0,0 +1,102 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/blues/note-go/notecard"
+)
+
+// Send random bytes to the notecard's binary buffer and read them back, verifying that they
+// survived the round trip intact and reporting the throughput in each direction
+func binaryTest(size int) (err error) {
+
+	// Make sure that the test fits in the binary buffer
+	err = firmwareRequire("-binary-test", firmwareMinBinary)
+	if err != nil {
+		return
+	}
+	var rsp notecard.Request
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "card.binary"})
+	if err != nil {
+		return
+	}
+	if size > int(rsp.Max) {
+		return fmt.Errorf("%d bytes exceeds the notecard's binary buffer maximum of %d bytes", size, rsp.Max)
+	}
+	cardTransactionRequest(notecard.Request{Req: "card.binary", Delete: true})
+
+	// Generate the test data
+	sent := make([]byte, size)
+	rand.Read(sent)
+	sentMD5 := fmt.Sprintf("%x", md5.Sum(sent))
+
+	// Send it
+	var encoded []byte
+	encoded, err = notecard.CobsEncode(sent, byte('\n'))
+	if err != nil {
+		return
+	}
+	began := time.Now()
+	req := notecard.Request{Req: "card.binary.put"}
+	req.Cobs = int32(len(encoded))
+	req.Status = sentMD5
+	_, err = cardTransactionRequest(req)
+	if err != nil {
+		return
+	}
+	err = card.SendBytes(append(encoded, byte('\n')))
+	if err != nil {
+		return
+	}
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "card.binary"})
+	if err != nil {
+		return
+	}
+	putElapsed := time.Since(began)
+	if int(rsp.Length) != size {
+		return fmt.Errorf("FAIL: notecard received %d of %d bytes", rsp.Length, size)
+	}
+
+	// Read it back
+	began = time.Now()
+	_, err = cardTransactionRequest(notecard.Request{Req: "card.binary.get"})
+	if err != nil {
+		return
+	}
+	var received []byte
+	received, err = card.ReceiveBytes()
+	if err != nil {
+		return
+	}
+	received, err = notecard.CobsDecode(bytes.TrimSuffix(received, []byte("\n")), byte('\n'))
+	if err != nil {
+		return
+	}
+	getElapsed := time.Since(began)
+	cardTransactionRequest(notecard.Request{Req: "card.binary", Delete: true})
+
+	// Report the results
+	fmt.Printf("     put: %d bytes in %d ms (%.0f bytes/sec)\n", size, putElapsed.Milliseconds(), float64(size)/putElapsed.Seconds())
+	fmt.Printf("     get: %d bytes in %d ms (%.0f bytes/sec)\n", len(received), getElapsed.Milliseconds(), float64(len(received))/getElapsed.Seconds())
+	receivedMD5 := fmt.Sprintf("%x", md5.Sum(received))
+	if !bytes.Equal(sent, received) {
+		offset := 0
+		for offset < len(sent) && offset < len(received) && sent[offset] == received[offset] {
+			offset++
+		}
+		return fmt.Errorf("FAIL: data differs starting at offset %d (sent %d bytes MD5 %s, received %d bytes MD5 %s)",
+			offset, len(sent), sentMD5, len(received), receivedMD5)
+	}
+	fmt.Printf("PASS: MD5 %s\n", receivedMD5)
+
+	return
+
+}
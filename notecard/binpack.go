@@ -284,6 +284,11 @@ func parseNumber(numstr string) (num int, err error) {
 
 // Determines whether or not a firmware image is a Notecard image or a User image
 func dfuIsNotecardFirmware(bin *[]byte) (isNotecardImage bool) {
+	return dfuNotecardFirmwareSignatureOffset(bin) != -1
+}
+
+// Returns the offset of the Notecard firmware signature within an image, or -1 if not present
+func dfuNotecardFirmwareSignatureOffset(bin *[]byte) (offset int) {
 
 	// NotecardFirmwareSignature is used to identify whether or not this firmware is a
 	// candidate for downloading onto notecards.  Note that this is not a security feature; if someone
@@ -292,7 +297,7 @@ func dfuIsNotecardFirmware(bin *[]byte) (isNotecardImage bool) {
 	// convenience and is just intended to keep people from inadvertently hurting themselves.
 	var NotecardFirmwareSignature = []byte{0x82, 0x1c, 0x6e, 0xb7, 0x18, 0xec, 0x4e, 0x6f, 0xb3, 0x9e, 0xc1, 0xe9, 0x8f, 0x22, 0xe9, 0xf6}
 
-	return bytes.Contains(*bin, NotecardFirmwareSignature)
+	return bytes.Index(*bin, NotecardFirmwareSignature)
 
 }
 
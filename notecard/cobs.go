@@ -0,0 +1,44 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/blues/note-go/notecard"
+)
+
+// Delimiter used to frame COBS-encoded binary transfers, exactly as in card.binary.put/get
+const cobsDelimiter = byte('\n')
+
+// Encode or decode a file using the same COBS framing as a live binary transfer, writing
+// the result to outfile or, if not specified, to stdout.  No notecard is required.
+func cobsFile(infile string, outfile string, decode bool) (err error) {
+
+	data, err := ioutil.ReadFile(infile)
+	if err != nil {
+		return
+	}
+
+	if decode {
+		data = bytes.TrimSuffix(data, []byte{cobsDelimiter})
+		data, err = notecard.CobsDecode(data, cobsDelimiter)
+	} else {
+		data, err = notecard.CobsEncode(data, cobsDelimiter)
+		data = append(data, cobsDelimiter)
+	}
+	if err != nil {
+		return
+	}
+
+	if outfile == "" {
+		_, err = os.Stdout.Write(data)
+		return
+	}
+	return ioutil.WriteFile(outfile, data, 0644)
+
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io/ioutil"
+	"os"
 	"strings"
 	"time"
 
@@ -18,6 +19,45 @@ import (
 	"github.com/golang/snappy"
 )
 
+// Set by -progress-json to report sideload progress as JSON on stderr rather than as text
+var dfuProgressJSON bool
+
+// Set by -resume to continue a sideload of the same image from where it was interrupted
+var dfuResume bool
+
+// Determine where to resume a previously-interrupted sideload of the image with the
+// specified MD5.  A resume is only possible if the card's dfu.status confirms both that the
+// image being loaded is this same one and how far it got; if the card's firmware reports
+// neither, as is the case unless it supports resumable sideloads, this returns 0 and the
+// sideload starts over.
+func dfuResumeOffset(filetype notehub.UploadType, md5 string, totalLen int) (offset int) {
+	name := "user"
+	if filetype == notehub.UploadTypeNotecardFirmware {
+		name = "card"
+	}
+	status, err := cardTransactionMap(map[string]interface{}{"req": "dfu.status", "name": name})
+	if err != nil {
+		return 0
+	}
+	body, _ := status["body"].(map[string]interface{})
+	if loadedMD5, _ := body["md5"].(string); loadedMD5 == "" || loadedMD5 != md5 {
+		return 0
+	}
+	offset = int(mapNumber(status, "offset"))
+	if offset <= 0 || offset >= totalLen {
+		return 0
+	}
+	return
+}
+
+// Report sideload progress as a single line of JSON on stderr
+func dfuProgress(progress map[string]interface{}) {
+	progressJSON, err := note.JSONMarshal(progress)
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "%s\n", progressJSON)
+	}
+}
+
 // Side-loads a file to the DFU area of the notecard, to avoid download
 func dfuSideload(filename string, verbose bool) (err error) {
 
@@ -25,7 +65,7 @@ func dfuSideload(filename string, verbose bool) (err error) {
 	// doing binary sideloads, and if so, how large.
 	binaryMax := 0
 	var rsp notecard.Request
-	rsp, err = card.TransactionRequest(notecard.Request{Req: "card.binary"})
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "card.binary"})
 	if note.ErrorContains(err, note.ErrCardIo) {
 		return err
 	}
@@ -65,7 +105,7 @@ func dfuSideload(filename string, verbose bool) (err error) {
 	if err != nil {
 		return
 	}
-	_, err = card.TransactionRequest(notecard.Request{Req: "card.time", Time: epochTime})
+	_, err = cardTransactionRequest(notecard.Request{Req: "card.time", Time: epochTime})
 	if err != nil {
 		return
 	}
@@ -75,7 +115,7 @@ func dfuSideload(filename string, verbose bool) (err error) {
 
 		fmt.Printf("placing notecard into DFU mode so that we can send file to its external flash storage\n")
 
-		_, err = card.TransactionRequest(notecard.Request{Req: "hub.set", Mode: "dfu"})
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", Mode: "dfu"})
 		if err != nil {
 			return
 		}
@@ -83,13 +123,13 @@ func dfuSideload(filename string, verbose bool) (err error) {
 		// Make sure we restore the mode on exit
 		defer func() {
 			fmt.Printf("restoring notecard so that it is no longer in DFU mode\n")
-			card.TransactionRequest(notecard.Request{Req: "hub.set", Mode: "dfu-completed"})
+			cardTransactionRequest(notecard.Request{Req: "hub.set", Mode: "dfu-completed"})
 		}()
 
 		// Wait until dfu status says that we're in DFU mode
 		for {
 			fmt.Printf("waiting for notecard to power-up the external storage\n")
-			_, err = card.TransactionRequest(notecard.Request{Req: "dfu.put"})
+			_, err = cardTransactionRequest(notecard.Request{Req: "dfu.put"})
 			if err != nil && !note.ErrorContains(err, note.ErrDFUNotReady) && !note.ErrorContains(err, note.ErrCardIo) {
 				return
 			}
@@ -144,13 +184,29 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 		return
 	}
 
+	// If requested, see if a previous attempt at loading this same image got partway through.
+	// Resuming skips the initial dfu.put, which would restart the transfer on the card, so it
+	// requires binary transfers, whose chunk length we know and which are never compressed.
+	resumeOffset := 0
+	if dfuResume {
+		if binaryMax > 0 {
+			resumeOffset = dfuResumeOffset(filetype, dbu.MD5, totalLen)
+		}
+		if resumeOffset == 0 {
+			fmt.Printf("notecard did not confirm a partial load of this image; starting from the beginning\n")
+		}
+	}
+
 	// Issue the first request, which is to initiate the DFU put
 	chunkLen := 0
 	compressionMode := ""
-	for {
+	if resumeOffset > 0 {
+		chunkLen = binaryMax
+	}
+	for chunkLen == 0 {
 		req = notecard.Request{Req: "dfu.put"}
 		req.Body = &body
-		rsp, err = card.TransactionRequest(req)
+		rsp, err = cardTransactionRequest(req)
 		if err != nil {
 			return
 		}
@@ -170,16 +226,19 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 		// the command line utility) we get a response that doesn't have the appropriate
 		// fields because we are out of sync.  This is defensive
 		// coding that ensures that we don't proceed until we get in sync.
-		if chunkLen > 0 {
-			break
+		if chunkLen == 0 {
+			time.Sleep(750)
 		}
-		time.Sleep(750)
 	}
 
 	// Send the chunk to sideload
-	offset := 0
-	lenRemaining := totalLen
+	offset := resumeOffset
+	lenRemaining := totalLen - resumeOffset
+	if resumeOffset > 0 {
+		fmt.Printf("resuming at offset %d\n", resumeOffset)
+	}
 	beganSecs := time.Now().UTC().Unix()
+	began := time.Now()
 	for lenRemaining > 0 {
 
 		// Determine how much to send
@@ -189,7 +248,16 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 		}
 
 		// Send the chunk
-		fmt.Printf("side-loading %d bytes (%.0f%% %d remaining)\n", thisLen, float64(lenRemaining*100)/float64(totalLen), lenRemaining)
+		if dfuProgressJSON {
+			elapsed := time.Since(began).Seconds()
+			bps := 0.0
+			if elapsed > 0 {
+				bps = float64(offset-resumeOffset) / elapsed
+			}
+			dfuProgress(map[string]interface{}{"offset": offset, "total": totalLen, "percent": offset * 100 / totalLen, "bps": int64(bps)})
+		} else {
+			fmt.Printf("side-loading %d bytes (%.0f%% %d remaining)\n", thisLen, float64(lenRemaining*100)/float64(totalLen), lenRemaining)
+		}
 		req = notecard.Request{Req: "dfu.put"}
 		req.Offset = int32(offset)
 		req.Length = int32(thisLen)
@@ -215,7 +283,7 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 			// Send the COBS data to the notecard
 			req2 := notecard.Request{Req: "card.binary.put"}
 			req2.Cobs = int32(len(payloadEncoded))
-			rsp, err = card.TransactionRequest(req2)
+			rsp, err = cardTransactionRequest(req2)
 			if err != nil {
 				return
 			}
@@ -227,7 +295,7 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 
 			// Verify that the binary made it to the notecard
 			var rsp2 notecard.Request
-			rsp2, err = card.TransactionRequest(notecard.Request{Req: "card.binary"})
+			rsp2, err = cardTransactionRequest(notecard.Request{Req: "card.binary"})
 			if err != nil {
 				return
 			}
@@ -243,7 +311,7 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 		}
 
 		// Perform the request
-		rsp, err = card.TransactionRequest(req)
+		rsp, err = cardTransactionRequest(req)
 		if err != nil {
 			if note.ErrorContains(err, note.ErrCardIo) {
 				// Just silently retry {io} errors
@@ -260,7 +328,7 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 
 		// Wait until the migration succeeds
 		for rsp.Pending {
-			rsp, err = card.TransactionRequest(notecard.Request{Req: "dfu.put"})
+			rsp, err = cardTransactionRequest(notecard.Request{Req: "dfu.put"})
 			if err != nil {
 				// Some Notecard firmware versions will return "firmware update is in progress", while
 				// newer versions should include {dfu-in-progress} in the error string if the DFU has
@@ -283,14 +351,19 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 
 	// Display summary
 	elapsedSecs := (time.Now().UTC().Unix() - beganSecs) + 1
-	fmt.Printf("%d seconds (%.0f Bps)\n", elapsedSecs, float64(totalLen)/float64(elapsedSecs))
+	sentLen := totalLen - resumeOffset
+	if dfuProgressJSON {
+		dfuProgress(map[string]interface{}{"done": true, "total": totalLen, "elapsed": elapsedSecs, "bps": int64(float64(sentLen) / float64(elapsedSecs))})
+	} else {
+		fmt.Printf("%d seconds (%.0f Bps)\n", elapsedSecs, float64(sentLen)/float64(elapsedSecs))
+	}
 
 	// Wait until the DFU has completed.  This is particularly important for notecard
 	// sideloads where we must restart the module.
 	if filetype == notehub.UploadTypeNotecardFirmware {
 		first := true
 		for i := 0; i < 90; i++ {
-			rsp, err = card.TransactionRequest(notecard.Request{Req: "dfu.status", Name: "card"})
+			rsp, err = cardTransactionRequest(notecard.Request{Req: "dfu.status", Name: "card"})
 			if err == nil && !rsp.Pending {
 				break
 			}
@@ -306,3 +379,53 @@ func loadBin(filetype notehub.UploadType, filename string, bin []byte, binaryMax
 	return
 
 }
+
+// Explicitly place the notecard into or out of DFU mode, for host firmware bring-up
+// workflows that manage the transfer of the binary themselves
+func dfuMode(mode string) (err error) {
+
+	// Map the argument to the hub.set mode
+	hubMode := ""
+	switch strings.ToLower(mode) {
+	case "on":
+		hubMode = "dfu"
+	case "off":
+		hubMode = "dfu-completed"
+	default:
+		return fmt.Errorf("-dfu-mode must be 'on' or 'off'")
+	}
+
+	// LoRa notecards have no DFU mode.  Older firmware doesn't report whether it's LoRa, in
+	// which case it's identified by its SKU.
+	var rsp notecard.Request
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "card.version"})
+	if err != nil {
+		return
+	}
+	if rsp.LoRa || strings.HasPrefix(strings.ToUpper(rsp.SKU), "NOTE-LW") {
+		return fmt.Errorf("DFU mode is not supported on LoRa notecards (%s)", rsp.SKU)
+	}
+
+	// Set the mode and report the result
+	_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", Mode: hubMode})
+	if err != nil {
+		return
+	}
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
+	if err != nil {
+		return
+	}
+	fmt.Printf("notecard mode is now '%s'\n", rsp.Mode)
+	if hubMode == "dfu" {
+		_, err = cardTransactionRequest(notecard.Request{Req: "dfu.put"})
+		if err == nil {
+			fmt.Printf("external storage is ready\n")
+		} else if note.ErrorContains(err, note.ErrDFUNotReady) {
+			fmt.Printf("waiting for notecard to power-up the external storage\n")
+			err = nil
+		}
+	}
+
+	return
+
+}
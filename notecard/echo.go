@@ -37,7 +37,7 @@ func echo(iterations int) (err error) {
 		rand.Read(bin)
 		req = notecard.Request{Req: "echo"}
 		req.Payload = &bin
-		rsp, err = card.TransactionRequest(req)
+		rsp, err = cardTransactionRequest(req)
 		if err != nil {
 			return
 		}
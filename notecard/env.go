@@ -0,0 +1,160 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Extract the per-variable source scope (device, fleet, project, or host default) from an
+// env.get response, for firmware that reports it.  Returns nil if the card doesn't.
+func envSources(rsp map[string]interface{}) (sources map[string]string) {
+	reported, _ := rsp["sources"].(map[string]interface{})
+	if len(reported) == 0 {
+		return
+	}
+	sources = map[string]string{}
+	for name, scope := range reported {
+		sources[name] = fmt.Sprint(scope)
+	}
+	return
+}
+
+// Display the notecard's effective environment variables and, where the card reports it,
+// the scope from which each value was taken after merging device, fleet, and project vars.
+// All variables are displayed if no name is specified.
+func envGet(name string) (err error) {
+
+	req := map[string]interface{}{"req": "env.get"}
+	if name != "" {
+		req["name"] = name
+	}
+	rsp, err := cardTransactionMap(req)
+	if err != nil {
+		return
+	}
+
+	// A single variable is returned as text rather than within the body
+	values := map[string]string{}
+	if name != "" {
+		values[name], _ = rsp["text"].(string)
+	} else {
+		body, _ := rsp["body"].(map[string]interface{})
+		for k, v := range body {
+			values[k] = fmt.Sprint(v)
+		}
+	}
+	if len(values) == 0 {
+		fmt.Printf("no environment variables are set\n")
+		return
+	}
+
+	names := []string{}
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	// Fall back to a flat list if the card doesn't say where each value came from
+	sources := envSources(rsp)
+	for _, k := range names {
+		if sources == nil {
+			fmt.Printf("%24s: %s\n", k, values[k])
+			continue
+		}
+		source := sources[k]
+		if source == "" {
+			source = "unknown"
+		}
+		fmt.Printf("%24s: %s (from %s)\n", k, values[k], source)
+	}
+	if sources == nil {
+		fmt.Printf("(this notecard does not report which scope each variable came from)\n")
+	}
+
+	return
+}
+
+// Set each of a list of NAME=VALUE environment variables on the notecard
+func envSet(assignments []string) (err error) {
+
+	for _, assignment := range assignments {
+		equals := strings.Index(assignment, "=")
+		if equals <= 0 {
+			return fmt.Errorf("-env-set must be of the form name=value: %s", assignment)
+		}
+		name := assignment[:equals]
+		value := assignment[equals+1:]
+		_, err = cardTransactionMap(map[string]interface{}{"req": "env.set", "name": name, "text": value})
+		if err != nil {
+			return fmt.Errorf("can't set %s: %s", name, err)
+		}
+		fmt.Printf("%24s: %s\n", name, value)
+	}
+
+	return
+}
+
+// Scopes reported by env.get whose variables come from notehub rather than being set locally
+var envNotehubSources = []string{"project", "fleet", "device"}
+
+// Capture the environment variables that were set locally on the notecard, so that they may
+// be restored after the notecard is factory reset.  Variables inherited from notehub must not
+// be replayed, because env.set would turn them into local overrides that permanently shadow
+// later changes made in notehub.  If the card doesn't report where each variable came from,
+// all are captured and a warning is shown.
+func envCapture() (vars map[string]string, err error) {
+
+	rsp, err := cardTransactionMap(map[string]interface{}{"req": "env.get"})
+	if err != nil {
+		return
+	}
+	sources := envSources(rsp)
+	vars = map[string]string{}
+	body, _ := rsp["body"].(map[string]interface{})
+	skipped := 0
+	for k, v := range body {
+		fromNotehub := false
+		for _, source := range envNotehubSources {
+			if sources[k] == source {
+				fromNotehub = true
+			}
+		}
+		if fromNotehub {
+			skipped++
+			continue
+		}
+		vars[k] = fmt.Sprint(v)
+	}
+
+	if sources == nil && len(vars) > 0 {
+		fmt.Printf("warning: this notecard does not report which variables came from notehub, so all %d will be restored as local overrides\n", len(vars))
+	} else if skipped > 0 {
+		fmt.Printf("%d environment variables inherited from notehub will not be restored\n", skipped)
+	}
+
+	return
+}
+
+// Restore environment variables captured by envCapture
+func envRestore(vars map[string]string) (err error) {
+
+	names := []string{}
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, err = cardTransactionMap(map[string]interface{}{"req": "env.set", "name": name, "text": vars[name]})
+		if err != nil {
+			return fmt.Errorf("can't restore %s: %s", name, err)
+		}
+	}
+	fmt.Printf("%d environment variables restored\n", len(names))
+
+	return
+}
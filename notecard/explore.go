@@ -19,7 +19,7 @@ func explore(includeReserved bool, pretty bool) (err error) {
 	req := notecard.Request{Req: notecard.ReqFileChanges}
 	req.Allow = includeReserved
 	var rsp notecard.Request
-	rsp, err = card.TransactionRequest(req)
+	rsp, err = cardTransactionRequest(req)
 	if err != nil {
 		return
 	}
@@ -47,7 +47,7 @@ func explore(includeReserved bool, pretty bool) (err error) {
 		req.Allow = includeReserved
 		req.Deleted = true
 		req.NotefileID = notefileID
-		rsp, err = card.TransactionRequest(req)
+		rsp, err = cardTransactionRequest(req)
 		if err != nil {
 			return
 		}
@@ -0,0 +1,255 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notecard"
+)
+
+// Prefix of the firmware info line embedded within firmware images
+const firmwareInfoPrefix = "firmware::info:"
+
+// Minimum firmware required for transfers through the card.binary buffer
+var firmwareMinBinary = FirmwareInfo{VerMajor: 5, VerMinor: 3, VerPatch: 1}
+
+// Set by -skip-firmware-check to attempt actions even on firmware that is too old
+var firmwareRequireSkip bool
+
+// FirmwareInfo is the version information embedded within a firmware image, and also
+// returned in the body of card.version
+type FirmwareInfo struct {
+	Org      string `json:"org,omitempty"`
+	Product  string `json:"product,omitempty"`
+	Version  string `json:"version,omitempty"`
+	VerMajor int    `json:"ver_major,omitempty"`
+	VerMinor int    `json:"ver_minor,omitempty"`
+	VerPatch int    `json:"ver_patch,omitempty"`
+	VerBuild int    `json:"ver_build,omitempty"`
+	Built    string `json:"built,omitempty"`
+}
+
+// Returns a printable version string for firmware info
+func (fi FirmwareInfo) String() string {
+	if fi.Version == "" && fi.VerMajor == 0 && fi.VerMinor == 0 && fi.VerPatch == 0 {
+		return "(unknown)"
+	}
+	return fmt.Sprintf("%s %d.%d.%d.%d", fi.Version, fi.VerMajor, fi.VerMinor, fi.VerPatch, fi.VerBuild)
+}
+
+// Compare two firmware versions, returning -1, 0, or 1
+func (fi FirmwareInfo) Compare(other FirmwareInfo) int {
+	a := []int{fi.VerMajor, fi.VerMinor, fi.VerPatch, fi.VerBuild}
+	b := []int{other.VerMajor, other.VerMinor, other.VerPatch, other.VerBuild}
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Extract the firmware info embedded within a firmware image
+func firmwareInfoFromImage(bin *[]byte) (fi FirmwareInfo, found bool) {
+	line := extractLine(bin, firmwareInfoPrefix)
+	if line == "" {
+		return
+	}
+	err := note.JSONUnmarshal([]byte(strings.TrimPrefix(line, firmwareInfoPrefix)), &fi)
+	found = err == nil
+	return
+}
+
+// Get the firmware info of the firmware currently running on the notecard
+func firmwareInfoFromCard() (fi FirmwareInfo, err error) {
+	var rsp map[string]interface{}
+	rsp, err = cardTransactionMap(map[string]interface{}{"req": "card.version"})
+	if err != nil {
+		return
+	}
+	body, present := rsp["body"].(map[string]interface{})
+	if present {
+		err = note.BodyToObject(&body, &fi)
+		if err != nil {
+			return
+		}
+	}
+	if fi.Version == "" {
+		fi.Version, _ = rsp["version"].(string)
+	}
+	return
+}
+
+// Fail with an actionable error if the notecard's firmware is older than what an action requires.
+// If the notecard's version can't be determined the action is allowed to proceed.
+func firmwareRequire(action string, min FirmwareInfo) (err error) {
+	if firmwareRequireSkip {
+		return
+	}
+	cardInfo, err := firmwareInfoFromCard()
+	if err != nil {
+		return
+	}
+	if cardInfo.VerMajor == 0 || cardInfo.Compare(min) >= 0 {
+		return
+	}
+	return fmt.Errorf("%s requires notecard firmware >= %d.%d.%d, but this notecard is running %s (use -skip-firmware-check to try anyway)",
+		action, min.VerMajor, min.VerMinor, min.VerPatch, cardInfo)
+}
+
+// Compare a local firmware image against what is running on the notecard
+func firmwareCheck(filename string) (err error) {
+
+	// Read the local image and extract what we know about it
+	var bin []byte
+	bin, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	localMD5 := fmt.Sprintf("%x", md5.Sum(bin))
+	localInfo, localInfoFound := firmwareInfoFromImage(&bin)
+	if !dfuIsNotecardFirmware(&bin) {
+		fmt.Printf("warning: %s does not appear to be notecard firmware\n", filename)
+	}
+
+	// Get the version of what is currently running on the card
+	var cardInfo FirmwareInfo
+	cardInfo, err = firmwareInfoFromCard()
+	if err != nil {
+		return
+	}
+
+	// Get the MD5 of the image most recently downloaded to the card, if any
+	cardMD5 := ""
+	rsp, err := cardTransactionMap(map[string]interface{}{"req": "dfu.status", "name": "card"})
+	if err == nil {
+		body, _ := rsp["body"].(map[string]interface{})
+		if body != nil {
+			cardMD5, _ = body["md5"].(string)
+		}
+	}
+	err = nil
+
+	// Display what we found
+	fmt.Printf("              Local File: %s\n", filename)
+	fmt.Printf("               Local MD5: %s\n", localMD5)
+	if localInfoFound {
+		fmt.Printf("           Local Version: %s\n", localInfo)
+	} else {
+		fmt.Printf("           Local Version: (no firmware info found in image)\n")
+	}
+	fmt.Printf("         Running Version: %s\n", cardInfo)
+	if cardMD5 != "" {
+		fmt.Printf("     Last Downloaded MD5: %s\n", cardMD5)
+	}
+
+	// Render a verdict
+	verdict := ""
+	switch {
+	case !localInfoFound:
+		verdict = "unable to compare: the local image contains no firmware info"
+	case localInfo.Product != "" && cardInfo.Product != "" && !strings.EqualFold(localInfo.Product, cardInfo.Product):
+		verdict = fmt.Sprintf("unrelated: local image is for '%s' but card is running '%s'", localInfo.Product, cardInfo.Product)
+	case localInfo.Compare(cardInfo) == 0 && cardMD5 != "" && cardMD5 == localMD5:
+		verdict = "card is running this exact build"
+	case localInfo.Compare(cardInfo) == 0:
+		verdict = "card is running the same version as this image"
+	case localInfo.Compare(cardInfo) < 0:
+		verdict = "local image is OLDER than what the card is running"
+	default:
+		verdict = "local image is NEWER than what the card is running"
+	}
+	fmt.Printf("                 Verdict: %s\n", verdict)
+
+	return
+
+}
+
+// Report whether a local image carries the Notecard firmware signature.  As with the signature
+// itself, this is a convenience to avoid sideloading the wrong file, not a security control:
+// it says nothing about whether or not the image is authentic.
+func firmwareSignCheck(filename string) (err error) {
+
+	var bin []byte
+	bin, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	offset := dfuNotecardFirmwareSignatureOffset(&bin)
+	if offset == -1 {
+		return fmt.Errorf("%s: notecard firmware signature NOT present; this image cannot be sideloaded as notecard firmware", filename)
+	}
+
+	fmt.Printf("%s: notecard firmware signature present at offset 0x%x (%d)\n", filename, offset, offset)
+	info, found := firmwareInfoFromImage(&bin)
+	if found {
+		fmt.Printf("%s: %s\n", filename, info)
+	}
+
+	return
+
+}
+
+// Returns true if a reported firmware version matches the wanted version, either exactly or
+// by prefix, with or without the leading product name such as "notecard-"
+func firmwareVersionMatches(reported string, wanted string) bool {
+	if reported == "" {
+		return false
+	}
+	if strings.HasPrefix(reported, wanted) {
+		return true
+	}
+	i := strings.Index(reported, "-")
+	return i != -1 && strings.HasPrefix(reported[i+1:], wanted)
+}
+
+// Wait until the notecard reports that it is running the specified firmware version,
+// tolerating the notecard being unresponsive while it restarts into the new firmware
+func firmwareWaitForVersion(wanted string, timeout string) (err error) {
+
+	waitFor := 10 * time.Minute
+	if timeout != "" {
+		waitFor, err = time.ParseDuration(timeout)
+		if err != nil {
+			return
+		}
+	}
+
+	began := time.Now()
+	lastVersion := ""
+	for {
+
+		rsp, err2 := cardTransactionRequest(notecard.Request{Req: "card.version"})
+		if err2 != nil {
+			if lastVersion != "(not responding)" {
+				fmt.Printf("notecard is not responding: %s\n", err2)
+				lastVersion = "(not responding)"
+			}
+		} else if rsp.Version != lastVersion {
+			fmt.Printf("notecard is running %s\n", rsp.Version)
+			lastVersion = rsp.Version
+			if firmwareVersionMatches(rsp.Version, wanted) {
+				return nil
+			}
+		}
+
+		if time.Since(began) > waitFor {
+			return fmt.Errorf("notecard did not report version %s within %s", wanted, waitFor)
+		}
+		time.Sleep(3 * time.Second)
+
+	}
+
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notecard"
+)
+
+// CardInfo is everything that -info gathers about the notecard.  Every key is present in its
+// JSON form, even if empty, so that scripts can rely upon it.
+type CardInfo struct {
+	Name           string                  `json:"name"`
+	ProductUID     string                  `json:"product"`
+	DeviceUID      string                  `json:"device"`
+	SN             string                  `json:"sn"`
+	Host           string                  `json:"host"`
+	Version        string                  `json:"version"`
+	SKU            string                  `json:"sku"`
+	Modem          string                  `json:"modem"`
+	ICCID          string                  `json:"iccid"`
+	IMSI           string                  `json:"imsi"`
+	IMEI           string                  `json:"imei"`
+	ICCIDExternal  string                  `json:"iccid_external"`
+	IMSIExternal   string                  `json:"imsi_external"`
+	Provisioned    string                  `json:"provisioned"`
+	UsedBytes      string                  `json:"used_bytes"`
+	SyncMode       string                  `json:"sync_mode"`
+	OutboundPeriod string                  `json:"outbound_period"`
+	InboundPeriod  string                  `json:"inbound_period"`
+	NotehubStatus  string                  `json:"notehub_status"`
+	LastSynced     string                  `json:"last_synced"`
+	Voltage        float64                 `json:"voltage"`
+	Temperature    float64                 `json:"temperature"`
+	GPSMode        string                  `json:"gps_mode"`
+	Location       string                  `json:"location"`
+	CurrentTime    string                  `json:"time"`
+	BootTime       string                  `json:"boot_time"`
+	Notefiles      string                  `json:"notefiles"`
+	StorageUsedPct int                     `json:"storage_used_pct"`
+	Env            *map[string]interface{} `json:"env"`
+	Errors         []string                `json:"errors"`
+}
+
+// A request issued by -info and how to extract what it learns into CardInfo
+type infoProbe struct {
+	req          string
+	notSupported bool // the request is not supported by all notecards, which isn't an error
+	extract      func(info *CardInfo, rsp notecard.Request)
+}
+
+// The requests issued by -info, in order.  To show more, add a probe here and a field to CardInfo.
+var infoProbes = []infoProbe{
+	{req: "card.version", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.DeviceUID = rsp.DeviceUID
+		info.Name = rsp.Name
+		info.SKU = rsp.SKU
+		info.Version = rsp.Version
+	}},
+	{req: "card.wireless", notSupported: true, extract: func(info *CardInfo, rsp notecard.Request) {
+		info.Modem = rsp.Net.ModemFirmware
+		info.IMEI = rsp.Net.Imei
+		info.IMSI = rsp.Net.Imsi
+		info.ICCID = rsp.Net.Iccid
+		info.IMSIExternal = rsp.Net.ImsiExternal
+		info.ICCIDExternal = rsp.Net.IccidExternal
+	}},
+	{req: "hub.get", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.SN = rsp.SN
+		info.Host = rsp.Host
+		info.ProductUID = rsp.ProductUID
+		info.SyncMode = rsp.Mode
+		if rsp.Minutes != 0 {
+			info.OutboundPeriod = fmt.Sprintf("%d minutes", rsp.Minutes)
+		}
+		if rsp.Outbound != 0 {
+			info.OutboundPeriod = fmt.Sprintf("%d minutes", rsp.Outbound)
+		}
+		if rsp.OutboundV != "" {
+			info.OutboundPeriod = rsp.OutboundV
+		}
+		if rsp.Hours != 0 {
+			info.InboundPeriod = fmt.Sprintf("%d hours", rsp.Hours)
+		}
+		if rsp.Inbound != 0 {
+			info.InboundPeriod = fmt.Sprintf("%d minutes", rsp.Inbound)
+		}
+		if rsp.InboundV != "" {
+			info.InboundPeriod = rsp.InboundV
+		}
+	}},
+	{req: "card.voltage", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.Voltage = rsp.Value
+	}},
+	{req: "card.temp", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.Temperature = rsp.Value
+	}},
+	{req: "card.location.mode", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.GPSMode = rsp.Mode
+		if rsp.Status != "" {
+			info.GPSMode += " (" + rsp.Status + ")"
+		}
+	}},
+	{req: "card.time", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.CurrentTime = formatEpochTime(float64(rsp.Time))
+	}},
+	{req: "card.location", extract: func(info *CardInfo, rsp notecard.Request) {
+		if rsp.Latitude != 0 || rsp.Longitude != 0 {
+			info.Location = fmt.Sprintf("%f,%f (%s)", rsp.Latitude, rsp.Longitude, rsp.LocationOLC)
+		}
+	}},
+	{req: "card.status", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.BootTime = formatEpochTime(float64(rsp.Time))
+		info.StorageUsedPct = int(rsp.Storage)
+	}},
+	{req: "hub.sync.status", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.LastSynced = formatEpochTime(float64(rsp.Time))
+	}},
+	{req: "hub.status", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.NotehubStatus = rsp.Status
+		if rsp.Connected {
+			info.NotehubStatus += " (connected)"
+		}
+	}},
+	{req: "card.usage.get", notSupported: true, extract: func(info *CardInfo, rsp notecard.Request) {
+		if rsp.Time > 0 {
+			info.Provisioned = formatEpochTime(float64(rsp.Time))
+		}
+		info.UsedBytes = fmt.Sprint(int(rsp.BytesSent + rsp.BytesReceived))
+	}},
+	{req: "env.get", extract: func(info *CardInfo, rsp notecard.Request) {
+		info.Env = rsp.Body
+	}},
+	{req: "file.changes", extract: func(info *CardInfo, rsp notecard.Request) {
+		if rsp.FileInfo == nil {
+			return
+		}
+		for notefileID, fileInfo := range *rsp.FileInfo {
+			if info.Notefiles != "" {
+				info.Notefiles += ", "
+			}
+			if fileInfo.Changes > 0 {
+				info.Notefiles += fmt.Sprintf("%s (%d)", notefileID, fileInfo.Changes)
+			} else {
+				info.Notefiles += notefileID
+			}
+		}
+	}},
+}
+
+// Gather information about the notecard, showing progress on stderr because there are many requests
+func infoGather() (info CardInfo, infoErr error) {
+
+	info.OutboundPeriod = "-"
+	info.InboundPeriod = "-"
+
+	for i, probe := range infoProbes {
+		fmt.Fprintf(os.Stderr, "\rgathering info (%d/%d)", i+1, len(infoProbes))
+		rsp, err := cardTransactionRequest(notecard.Request{Req: probe.req})
+		if err == nil {
+			probe.extract(&info, rsp)
+		} else if !probe.notSupported || !strings.Contains(err.Error(), "{not-supported}") {
+			infoErr = accumulateInfoErr(infoErr, err)
+			info.Errors = append(info.Errors, fmt.Sprintf("%s: %s", probe.req, err))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", 32))
+
+	return
+
+}
+
+// Format notecard information for display
+func infoText(info CardInfo) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "\n%s\n", info.Name)
+	productUID := info.ProductUID
+	if productUID == "" {
+		productUID = "*** Product UID is not set. Please use notehub.io to create a project and a product UID ***"
+	}
+	fmt.Fprintf(&b, "              ProductUID: %s\n", productUID)
+	fmt.Fprintf(&b, "               DeviceUID: %s\n", info.DeviceUID)
+	fmt.Fprintf(&b, "           Serial Number: %s\n", info.SN)
+	fmt.Fprintf(&b, "            Notehub Host: %s\n", info.Host)
+	fmt.Fprintf(&b, "        Firmware Version: %s\n", info.Version)
+	fmt.Fprintf(&b, "                     SKU: %s\n", info.SKU)
+	if info.Modem != "" {
+		fmt.Fprintf(&b, "                   Modem: %s\n", info.Modem)
+		fmt.Fprintf(&b, "                   ICCID: %s\n", info.ICCID)
+		fmt.Fprintf(&b, "                    IMSI: %s\n", info.IMSI)
+		fmt.Fprintf(&b, "                    IMEI: %s\n", info.IMEI)
+	}
+	if info.ICCIDExternal != "" {
+		fmt.Fprintf(&b, "          External ICCID: %s\n", info.ICCIDExternal)
+		fmt.Fprintf(&b, "           External IMSI: %s\n", info.IMSIExternal)
+	}
+	if info.Provisioned != "" {
+		fmt.Fprintf(&b, "             Provisioned: %s\n", info.Provisioned)
+	}
+	if info.UsedBytes != "" {
+		fmt.Fprintf(&b, "       Used Over-the-Air: %s bytes\n", info.UsedBytes)
+	}
+	fmt.Fprintf(&b, "               Sync Mode: %s\n", info.SyncMode)
+	fmt.Fprintf(&b, "    Sync Outbound Period: %s\n", info.OutboundPeriod)
+	fmt.Fprintf(&b, "          Inbound Period: %s\n", info.InboundPeriod)
+	fmt.Fprintf(&b, "          Notehub Status: %s\n", info.NotehubStatus)
+	fmt.Fprintf(&b, "             Last Synced: %s\n", info.LastSynced)
+	fmt.Fprintf(&b, "                 Voltage: %0.02fV\n", info.Voltage)
+	fmt.Fprintf(&b, "             Temperature: %0.02fC\n", info.Temperature)
+	fmt.Fprintf(&b, "                GPS Mode: %s\n", info.GPSMode)
+	fmt.Fprintf(&b, "                Location: %s\n", info.Location)
+	fmt.Fprintf(&b, "            Current Time: %s\n", info.CurrentTime)
+	fmt.Fprintf(&b, "               Boot Time: %s\n", info.BootTime)
+	fmt.Fprintf(&b, "               Notefiles: %s\n", info.Notefiles)
+	fmt.Fprintf(&b, "   Notefile Storage Used: %d%%\n", info.StorageUsedPct)
+	env := ""
+	if info.Env != nil {
+		envJSON, _ := note.JSONMarshalIndent(info.Env, "                          ", "  ")
+		env = strings.TrimSuffix(string(envJSON), "\n")
+	}
+	fmt.Fprintf(&b, "                     Env: %v\n", env)
+	return b.Bytes()
+}
+
+// Display information about the notecard, as text or as JSON, to the console or to a file
+func infoShow(asJSON bool, pretty bool, sortKeys bool, outfile string) (err error) {
+
+	info, infoErr := infoGather()
+
+	// In JSON, errors are reported within the object rather than by failing
+	var out []byte
+	if asJSON {
+		infoErr = nil
+		if info.Env == nil {
+			info.Env = &map[string]interface{}{}
+		}
+		if info.Errors == nil {
+			info.Errors = []string{}
+		}
+		out, err = outputJSON(info, pretty, sortKeys)
+		if err != nil {
+			return
+		}
+		out = append(out, '\n')
+	} else {
+		out = infoText(info)
+	}
+
+	if outfile != "" {
+		err = ioutil.WriteFile(outfile, out, 0644)
+		if err != nil {
+			return
+		}
+	} else {
+		fmt.Printf("%s", out)
+	}
+
+	return infoErr
+
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/notecard"
+)
+
+// Default number of transactions performed by -io-benchmark
+const ioBenchmarkCountDefault = 100
+
+// Results of benchmarking a single interface
+type IOBenchmarkResult struct {
+	Interface    string
+	Port         string
+	Transactions int
+	Errors       int
+	Bytes        int
+	Elapsed      time.Duration
+}
+
+// Determine the port to use for an interface, preferring the configured one
+func ioBenchmarkPort(iface string) (port string, portConfig int) {
+	port = lib.Config.IPort[iface].Port
+	portConfig = lib.Config.IPort[iface].PortConfig
+	if port != "" {
+		return
+	}
+	var ports []string
+	if iface == notecard.NotecardInterfaceSerial {
+		_, _, ports, _ = notecard.SerialPorts()
+	}
+	if iface == notecard.NotecardInterfaceI2C {
+		ports, _, _, _ = notecard.I2CPorts()
+	}
+	if len(ports) > 0 {
+		port = ports[0]
+	}
+	return
+}
+
+// Perform a fixed number of transactions on the open notecard, counting rather than
+// stopping at errors.  This is the same loop as -commtest, but bounded.
+func ioBenchmarkRun(count int) (result IOBenchmarkResult) {
+
+	// Turn off debug output and tracing because they interfere with rapid transaction I/O
+	card.DebugOutput(false, false)
+	cardTransactionRequest(notecard.Request{Req: "card.io", Mode: "trace-off"})
+
+	reqJSON := []byte("{\"req\":\"card.version\"}")
+	began := time.Now()
+	lastMessage := time.Now()
+	for result.Transactions < count {
+		rspJSON, err := cardTransactionJSON(reqJSON)
+		result.Transactions++
+		if err != nil {
+			result.Errors++
+		} else {
+			result.Bytes += len(reqJSON) + len(rspJSON)
+		}
+		if time.Since(lastMessage).Seconds() > 2 {
+			lastMessage = time.Now()
+			fmt.Printf("%d of %d transactions (%d errors)\n", result.Transactions, count, result.Errors)
+		}
+	}
+	result.Elapsed = time.Since(began)
+
+	return
+}
+
+// Measure the error rate and throughput of the current interface or, when "both" is
+// specified, of serial and I2C side by side
+func ioBenchmark(which string, count int) (err error) {
+
+	if count <= 0 {
+		count = ioBenchmarkCountDefault
+	}
+
+	var results []IOBenchmarkResult
+	switch which {
+
+	case "", "current":
+		result := ioBenchmarkRun(count)
+		result.Interface = cardOpenInterface
+		result.Port = cardOpenPort
+		results = append(results, result)
+
+	case notecard.NotecardInterfaceSerial, notecard.NotecardInterfaceI2C, "both":
+		ifaces := []string{which}
+		if which == "both" {
+			ifaces = []string{notecard.NotecardInterfaceSerial, notecard.NotecardInterfaceI2C}
+		}
+
+		// Put the notecard back the way we found it when done
+		origInterface, origPort, origConfig := cardOpenInterface, cardOpenPort, cardOpenConfig
+		defer func() {
+			if card != nil {
+				card.Close()
+			}
+			cardOpen(origInterface, origPort, origConfig)
+		}()
+
+		for _, iface := range ifaces {
+			port, portConfig := ioBenchmarkPort(iface)
+			if port == "" {
+				fmt.Printf("%s: no port available, skipping\n", iface)
+				continue
+			}
+			if card != nil {
+				card.Close()
+			}
+			err = cardOpen(iface, port, portConfig)
+			if err != nil {
+				fmt.Printf("%s: can't open %s: %s\n", iface, port, err)
+				err = nil
+				continue
+			}
+			fmt.Printf("benchmarking %s on %s\n", iface, port)
+			result := ioBenchmarkRun(count)
+			result.Interface = iface
+			result.Port = port
+			results = append(results, result)
+		}
+
+	default:
+		return fmt.Errorf("-io-benchmark must be current, serial, i2c, or both")
+
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no interface could be benchmarked")
+	}
+
+	// Report side by side
+	fmt.Printf("\nsegments of %d bytes with %dms between them\n", notecard.RequestSegmentMaxLen, notecard.RequestSegmentDelayMs)
+	fmt.Printf("%-8s %-24s %8s %8s %8s %10s %10s\n", "IFACE", "PORT", "COUNT", "ERRORS", "ERR%", "TXN/SEC", "BYTES/SEC")
+	for _, r := range results {
+		secs := r.Elapsed.Seconds()
+		if secs <= 0 {
+			secs = 1
+		}
+		fmt.Printf("%-8s %-24s %8d %8d %7.1f%% %10.1f %10.0f\n", r.Interface, r.Port, r.Transactions, r.Errors,
+			float64(r.Errors*100)/float64(r.Transactions), float64(r.Transactions-r.Errors)/secs, float64(r.Bytes)/secs)
+	}
+
+	return
+}
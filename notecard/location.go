@@ -0,0 +1,134 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parse a "lat,lon" pair, validating that each is within range
+func locationParse(latlon string) (lat float64, lon float64, err error) {
+	parts := strings.Split(latlon, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("location must be specified as <lat>,<lon>")
+	}
+	lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("latitude must be a number between -90 and 90")
+	}
+	lon, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("longitude must be a number between -180 and 180")
+	}
+	return
+}
+
+// Configure the notecard with a fixed location, and confirm that the notecard reports it
+func locationSet(latlon string) (err error) {
+
+	lat, lon, err := locationParse(latlon)
+	if err != nil {
+		return
+	}
+
+	_, err = cardTransactionMap(map[string]interface{}{"req": "card.location.mode", "mode": "fixed", "lat": lat, "lon": lon})
+	if err != nil {
+		return
+	}
+
+	// Read it back
+	mode, err := cardTransactionMap(map[string]interface{}{"req": "card.location.mode"})
+	if err != nil {
+		return
+	}
+	location, err := cardTransactionMap(map[string]interface{}{"req": "card.location"})
+	if err != nil && !strings.Contains(err.Error(), "{location-") {
+		return
+	}
+	err = nil
+	actualMode, _ := mode["mode"].(string)
+	actualLat := mapNumber(location, "lat")
+	actualLon := mapNumber(location, "lon")
+	fmt.Printf("%24s: %s\n", "Location Mode", actualMode)
+	fmt.Printf("%24s: %f,%f\n", "Location", actualLat, actualLon)
+	if actualMode != "fixed" || math.Abs(actualLat-lat) > 0.0001 || math.Abs(actualLon-lon) > 0.0001 {
+		err = fmt.Errorf("notecard did not accept the fixed location %f,%f", lat, lon)
+	}
+
+	return
+
+}
+
+// Configure whether the notecard acquires its location by GPS, by cell tower triangulation,
+// or both, and report the configuration along with the most recent location and its source
+func locationMethod(method string) (err error) {
+
+	var gpsMode, triangulateMode string
+	switch method {
+	case "gps":
+		gpsMode = "periodic"
+		triangulateMode = "-"
+	case "cell":
+		gpsMode = "off"
+		triangulateMode = "cell"
+	case "all":
+		gpsMode = "periodic"
+		triangulateMode = "wifi,cell"
+	default:
+		return fmt.Errorf("location method must be gps, cell, or all")
+	}
+
+	_, err = cardTransactionMap(map[string]interface{}{"req": "card.location.mode", "mode": gpsMode})
+	if err != nil {
+		return
+	}
+	_, err = cardTransactionMap(map[string]interface{}{"req": "card.triangulate", "mode": triangulateMode})
+	if err != nil {
+		return
+	}
+
+	// Report what is now active
+	mode, err := cardTransactionMap(map[string]interface{}{"req": "card.location.mode"})
+	if err != nil {
+		return
+	}
+	triangulate, err := cardTransactionMap(map[string]interface{}{"req": "card.triangulate"})
+	if err != nil {
+		return
+	}
+	activeGPS, _ := mode["mode"].(string)
+	activeTriangulate, _ := triangulate["mode"].(string)
+	if activeTriangulate == "" {
+		activeTriangulate = "off"
+	}
+	fmt.Printf("%24s: %s\n", "GPS Mode", activeGPS)
+	fmt.Printf("%24s: %s\n", "Triangulation", activeTriangulate)
+
+	// Report the most recent location, and how it was obtained
+	location, err := cardTransactionMap(map[string]interface{}{"req": "card.location"})
+	if err != nil && !strings.Contains(err.Error(), "{location-") {
+		return
+	}
+	err = nil
+	if mapNumber(location, "lat") == 0 && mapNumber(location, "lon") == 0 {
+		fmt.Printf("%24s: (none)\n", "Last Location")
+		return
+	}
+	source, _ := location["mode"].(string)
+	if status, _ := location["status"].(string); status != "" {
+		source = status
+	}
+	fmt.Printf("%24s: %f,%f\n", "Last Location", mapNumber(location, "lat"), mapNumber(location, "lon"))
+	fmt.Printf("%24s: %s\n", "Location Time", formatEpochTime(mapNumber(location, "time")))
+	if source != "" {
+		fmt.Printf("%24s: %s\n", "Location Source", source)
+	}
+
+	return
+
+}
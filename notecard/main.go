@@ -14,6 +14,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/blues/note-cli/lib"
@@ -56,8 +57,20 @@ func main() {
 	// Process actions
 	var actionPretty bool
 	flag.BoolVar(&actionPretty, "pretty", false, "format JSON output indented")
+	var actionOutputFormat string
+	flag.StringVar(&actionOutputFormat, "output-format", "json", "format of -req responses: json or template")
+	var actionTemplate string
+	flag.StringVar(&actionTemplate, "template", "", "with -output-format template, a Go text/template such as '{{.DeviceUID}} {{.Version}}'")
+	var actionTemplateFile string
+	flag.StringVar(&actionTemplateFile, "template-file", "", "with -output-format template, a file containing the template")
+	var actionJSONCompact bool
+	flag.BoolVar(&actionJSONCompact, "json-compact", false, "format JSON output compactly, even if -pretty is specified")
+	var actionJSONSortKeys bool
+	flag.BoolVar(&actionJSONSortKeys, "json-sort-keys", false, "format JSON output with keys in sorted order, for stable diffs")
 	var actionRequest string
-	flag.StringVar(&actionRequest, "req", "", "perform the specified request (in quotes)")
+	flag.StringVar(&actionRequest, "req", "", "perform the specified request (in quotes), or read it from stdin with - or from a file with @file")
+	var actionAssert multiFlag
+	flag.Var(&actionAssert, "assert", "with -req, fail if the response doesn't satisfy a condition such as 'err==\"\"' (may be repeated)")
 	var actionWhenConnected bool
 	flag.BoolVar(&actionWhenConnected, "when-connected", false, "wait until connected")
 	var actionWhenDisconnected bool
@@ -68,58 +81,183 @@ func main() {
 	flag.BoolVar(&actionVerbose, "verbose", false, "display notecard requests and responses")
 	var actionWhenSynced bool
 	flag.BoolVar(&actionWhenSynced, "when-synced", false, "sync if needed and wait until sync completed")
+	var actionSyncTimeout int
+	flag.IntVar(&actionSyncTimeout, "sync-timeout", 0, "fail -when-synced, -when-connected, or -when-disconnected if not satisfied within this many minutes (default 0 = forever)")
 	var actionReserved bool
 	flag.BoolVar(&actionReserved, "reserved", false, "when exploring, include reserved notefiles")
 	var actionExplore bool
 	flag.BoolVar(&actionExplore, "explore", false, "explore the contents of the device")
 	var actionFactory bool
 	flag.BoolVar(&actionFactory, "factory", false, "reset notecard to factory defaults")
+	var actionKeepEnv bool
+	flag.BoolVar(&actionKeepEnv, "keep-env", false, "with -factory, restore the notecard's locally-set environment variables after the reset (if the notecard can't tell which were inherited from notehub, those too are restored, as local overrides)")
 	var actionFormat bool
 	flag.BoolVar(&actionFormat, "format", false, "reset notecard's notefile storage but retain configuration")
 	var actionInput string
 	flag.StringVar(&actionInput, "input", "", "add the contents of this file as a payload to the request")
 	var actionOutput string
 	flag.StringVar(&actionOutput, "output", "", "output file")
+	var actionOutputSplit string
+	flag.StringVar(&actionOutputSplit, "output-split", "", "write the payload of each response to a numbered file such as prefix-0001.bin")
+	var actionOutputDir string
+	flag.StringVar(&actionOutputDir, "output-dir", "", "with card.binary.get, write the payload into this directory, named by its MD5")
+	var actionOutputNameTemplate string
+	flag.StringVar(&actionOutputNameTemplate, "output-name-template", "", "with -output-dir, a template for the file name using {{.MD5}}, {{.Length}}, and {{.Time}}")
+	var actionPayloadEncoding string
+	flag.StringVar(&actionPayloadEncoding, "payload-encoding", "", "text, hex, base64, or raw: how a -req payload is given and a response payload is shown (raw requires -output)")
+	var actionOutputPayloadOnly bool
+	flag.BoolVar(&actionOutputPayloadOnly, "output-payload-only", false, "with -output, write only the response's binary payload to the file and don't display the JSON response")
 	var actionLog string
 	flag.StringVar(&actionLog, "log", "", "add a text string to the _log.qo notefile")
 	var actionTrace bool
 	flag.BoolVar(&actionTrace, "trace", false, "watch Notecard's trace output")
+	var actionTraceTimestamps bool
+	flag.BoolVar(&actionTraceTimestamps, "trace-timestamps", false, "with -trace, prefix each line with the local time it was received")
+	var actionTraceColor bool
+	flag.BoolVar(&actionTraceColor, "trace-color", false, "with -trace, highlight lines containing errors")
 	var actionPlayground bool
 	flag.BoolVar(&actionPlayground, "play", false, "enter JSON request/response playground")
 	var actionPlaytime int
 	flag.IntVar(&actionPlaytime, "playtime", 0, "enter number of minutes to play")
+	var actionReconnect bool
+	flag.BoolVar(&actionReconnect, "reconnect", false, "turn the notecard's radio off and back on, then wait until it reconnects to notehub")
 	var actionSync bool
 	flag.BoolVar(&actionSync, "sync", false, "manually initiate a sync")
+	var actionSyncCancel bool
+	flag.BoolVar(&actionSyncCancel, "sync-cancel", false, "cancel a sync that is in progress and show the resulting sync status")
 	var actionProduct string
 	flag.StringVar(&actionProduct, "product", "", "set product UID")
 	var actionSN string
 	flag.StringVar(&actionSN, "sn", "", "set serial number")
 	var actionInfo bool
 	flag.BoolVar(&actionInfo, "info", false, "show information about the Notecard")
+	var actionJSON bool
+	flag.BoolVar(&actionJSON, "json", false, "with -info, show the information as JSON")
+	var actionInfoJSON bool
+	flag.BoolVar(&actionInfoJSON, "info-json", false, "same as -info -json")
 	var actionHub string
 	flag.StringVar(&actionHub, "hub", "", "set notehub domain")
 	var actionWatchLevel int
 	flag.IntVar(&actionWatchLevel, "watch", -1, "watch ongoing sync status of a given level (0-5)")
+	var actionWatchSeconds int
+	flag.IntVar(&actionWatchSeconds, "watch-seconds", 0, "with -watch, stop after this many seconds (default 0 = forever)")
+	var actionWatchCount int
+	flag.IntVar(&actionWatchCount, "watch-count", 0, "with -watch, stop after this many status lines (default 0 = unlimited)")
 	var actionCommtest bool
 	flag.BoolVar(&actionCommtest, "commtest", false, "perform repetitive request/response test to validate comms with the Notecard")
+	var actionCobsEncode string
+	flag.StringVar(&actionCobsEncode, "cobs-encode", "", "COBS-encode this file as for card.binary.put, writing to -output or stdout")
+	var actionCobsDecode string
+	flag.StringVar(&actionCobsDecode, "cobs-decode", "", "decode this COBS-encoded file as for card.binary.get, writing to -output or stdout")
+	var actionIOBenchmark string
+	flag.StringVar(&actionIOBenchmark, "io-benchmark", "", "measure error rate and throughput on the current, serial, or i2c interface, or on both side by side")
+	var actionIOBenchmarkCount int
+	flag.IntVar(&actionIOBenchmarkCount, "io-benchmark-count", ioBenchmarkCountDefault, "with -io-benchmark, the number of transactions to perform on each interface")
 	var actionSetup string
 	flag.StringVar(&actionSetup, "setup", "", "issue requests sequentially as stored in the specified .json file")
+	var actionSetupResume bool
+	flag.BoolVar(&actionSetupResume, "setup-resume", false, "with -setup, skip requests already applied to this notecard by a prior interrupted -setup")
+	var actionSetupRestart bool
+	flag.BoolVar(&actionSetupRestart, "setup-restart", false, "with -setup, ignore any prior progress and track progress of this -setup from the beginning")
 	var actionSetupSKU string
 	flag.StringVar(&actionSetupSKU, "setup-sku", "", "configure a notecard for self-setup even after factory restore, with  requests in the specified .json file")
+	var actionSetupDump string
+	flag.StringVar(&actionSetupDump, "setup-dump", "", "write the notecard's configuration to a .json file in the format used by -setup")
+	var actionSetupDumpIdentity bool
+	flag.BoolVar(&actionSetupDumpIdentity, "setup-dump-identity", false, "with -setup-dump, include identity such as the serial number")
 	var actionScan string
 	flag.StringVar(&actionScan, "scan", "", "scan a batch of notecards to collect info or to set them up")
+	var actionBatchDelay string
+	flag.StringVar(&actionBatchDelay, "batch-delay", "", "with -scan, time to wait between successive notecards, or with -scan-parallel between starting each, such as 500ms or 5s")
+	var actionScanParallel int
+	flag.IntVar(&actionScanParallel, "scan-parallel", 0, "with -scan, set up all notecards attached by serial, up to this many at a time")
+	flag.BoolVar(&scanOnce, "scan-once", false, "with -scan, stop after a single notecard has been processed")
 	var actionProvision string
 	flag.StringVar(&actionProvision, "provision", "", "provision into carrier account using AccountSID:AuthTOKEN")
 	var actionDFUPackage string
 	flag.StringVar(&actionDFUPackage, "binpack", "", "package multiple .bin's for DFU into a single .bins package")
+	var actionProbe string
+	flag.StringVar(&actionProbe, "probe", "", "non-destructively identify whether a notecard is on the specified port, without saving it")
 	var actionFast bool
 	flag.BoolVar(&actionFast, "fast", false, "use low timeouts and big buffers when sending to notecard knowing that {io} errors are to be expected")
+	var actionSegmentMax int
+	flag.IntVar(&actionSegmentMax, "segment-max", 0, "maximum length in bytes of each segment of a request sent to the notecard (16-8192)")
+	var actionSegmentDelay int
+	flag.IntVar(&actionSegmentDelay, "segment-delay", -1, "delay in milliseconds between segments of a request sent to the notecard (0-1000)")
+	var actionDFUMode string
+	flag.StringVar(&actionDFUMode, "dfu-mode", "", "place the notecard into (on) or take it out of (off) DFU mode without sideloading")
 	var actionSideload string
 	flag.StringVar(&actionSideload, "sideload", "", "side-load a .bin or .bins into the notecard's storage")
+	flag.BoolVar(&dfuResume, "resume", false, "with -sideload, resume a previously-interrupted sideload of the same file")
+	flag.BoolVar(&dfuProgressJSON, "progress-json", false, "with -sideload, report progress as one JSON object per line on stderr")
 	var actionEcho int
 	flag.IntVar(&actionEcho, "echo", 0, "perform <N> iterations of a communications reliability test to the notecard")
 	var actionVersion bool
 	flag.BoolVar(&actionVersion, "version", false, "print the current version of the CLI")
+	var actionFirmwareCheck string
+	flag.StringVar(&actionFirmwareCheck, "firmware-check", "", "compare a local notecard firmware .bin against the firmware running on the notecard")
+	var actionWaitForVersion string
+	flag.StringVar(&actionWaitForVersion, "wait-for-version", "", "wait until the notecard is running the specified firmware version (exact or prefix)")
+	var actionWaitTimeout string
+	flag.StringVar(&actionWaitTimeout, "wait-timeout", "", "maximum time to wait, such as 90s or 15m (default 10m)")
+	var actionFirmwareSignCheck string
+	flag.StringVar(&actionFirmwareSignCheck, "firmware-sign-check", "", "report whether a .bin contains the notecard firmware signature (a convenience check, not a security control)")
+	var actionUsage bool
+	flag.BoolVar(&actionUsage, "usage", false, "show a detailed breakdown of the notecard's data usage")
+	flag.BoolVar(&firmwareRequireSkip, "skip-firmware-check", false, "attempt actions even if the notecard's firmware is older than they require")
+	flag.BoolVar(&cardReconnectOnReset, "serial-reconnect-on-reset", false, "re-open the port and continue if it disappears after a request restarts the notecard")
+	var actionReqFile string
+	flag.StringVar(&actionReqFile, "reqfile", "", "send each JSON request in the specified newline-delimited file, printing each response")
+	var actionContinueOnError bool
+	flag.BoolVar(&actionContinueOnError, "continue-on-error", false, "with -reqfile, continue with the next request after a transport error")
+	var actionTimeout int
+	flag.IntVar(&actionTimeout, "timeout", 0, "fail any single transaction with the notecard that takes longer than this many seconds")
+	var actionReadout string
+	flag.StringVar(&actionReadout, "readout", "", "repeatedly perform the specified request, showing the value of its -field")
+	var actionField string
+	flag.StringVar(&actionField, "field", "", "with -readout, the response field to show, such as value or body.temp")
+	var actionInterval string
+	flag.StringVar(&actionInterval, "interval", "", "with -readout, the time between requests such as 500ms or 5s (default 2s)")
+	var actionReqLoop bool
+	flag.BoolVar(&actionReqLoop, "req-loop", false, "read one JSON request per line from stdin, writing one JSON response per line to stdout, until end of input")
+	var actionLocationSet string
+	flag.StringVar(&actionLocationSet, "location-set", "", "set a fixed location for a stationary notecard as <lat>,<lon>")
+	var actionLocationMethod string
+	flag.StringVar(&actionLocationMethod, "location-method", "", "acquire location by gps, cell (tower triangulation), or all, and show the most recent location")
+	var actionVoltageMode string
+	flag.StringVar(&actionVoltageMode, "voltage-mode", "", "set the notecard's voltage profile (lipo, l91, alkaline, tad, lic, default, or custom level:volts thresholds) and show it")
+	var actionVoltageModeShow bool
+	flag.BoolVar(&actionVoltageModeShow, "voltage-mode-show", false, "show the notecard's voltage profile")
+	var actionEnvGet string
+	flag.StringVar(&actionEnvGet, "env-get", "", "show the value and source scope of an environment variable")
+	var actionEnvGetAll bool
+	flag.BoolVar(&actionEnvGetAll, "env-get-all", false, "show the values and source scopes of all environment variables")
+	var actionEnvSet multiFlag
+	flag.Var(&actionEnvSet, "env-set", "set an environment variable on the notecard as name=value (may be repeated)")
+	var actionPower bool
+	flag.BoolVar(&actionPower, "power", false, "show the notecard's voltage, power source, and voltage trend")
+	var actionAttnSleep int
+	flag.IntVar(&actionAttnSleep, "attn-sleep", 0, "put the host to sleep via ATTN, waking it after the specified number of seconds")
+	var actionAttnPayload string
+	flag.StringVar(&actionAttnPayload, "attn-payload", "", "with -attn-sleep, a file whose contents the notecard holds for the host until it wakes; with -attn-get, the file to which to write it")
+	var actionAttnGet bool
+	flag.BoolVar(&actionAttnGet, "attn-get", false, "show the ATTN configuration and retrieve any payload held for the host")
+	var actionModemInfo bool
+	flag.BoolVar(&actionModemInfo, "modem-info", false, "show the notecard's modem identity, registration state, and signal diagnostics")
+	var actionReboot bool
+	flag.BoolVar(&actionReboot, "reboot", false, "restart the notecard")
+	var actionRebootWait bool
+	flag.BoolVar(&actionRebootWait, "reboot-wait", false, "with -reboot, wait for the notecard to respond again and show its firmware version")
+	var actionModemReset bool
+	flag.BoolVar(&actionModemReset, "modem-reset", false, "reset the notecard's modem and show its state once the notecard returns")
+	var actionBinaryTest int
+	flag.IntVar(&actionBinaryTest, "binary-test", 0, "verify the integrity of a card.binary round trip of the specified number of random bytes")
+	var actionNotefileStats bool
+	flag.BoolVar(&actionNotefileStats, "notefile-stats", false, "show the number of notes in each notefile, largest first")
+	var actionUsageReset bool
+	flag.BoolVar(&actionUsageReset, "usage-reset", false, "reset the notecard's data usage counters")
+	var actionNTNStatus bool
+	flag.BoolVar(&actionNTNStatus, "ntn-status", false, "show the satellite (NTN) connectivity status of the notecard")
 
 	// Parse these flags and also the note tool config flags
 	err := lib.FlagParse(true, false)
@@ -128,6 +266,20 @@ func main() {
 		os.Exit(exitFail)
 	}
 
+	// Shorthand for structured info
+	if actionInfoJSON {
+		actionInfo = true
+		actionJSON = true
+	}
+
+	// Bound every transaction
+	cardTimeout = time.Duration(actionTimeout) * time.Second
+
+	// Compact formatting takes precedence over pretty formatting
+	if actionJSONCompact {
+		actionPretty = false
+	}
+
 	// If no action specified (i.e. just -port x), exit so that we don't touch the wrong port
 	if len(os.Args) == 1 {
 		fmt.Printf("Command arguments:\n")
@@ -170,12 +322,87 @@ func main() {
 		}
 	}
 
+	// A request of - or @- is read from stdin, and @file from a file
+	if actionRequest == "-" || actionRequest == "@-" {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Printf("can't read request from stdin: %s\n", err)
+			os.Exit(exitFail)
+		}
+		actionRequest = strings.TrimSpace(string(contents))
+	} else if strings.HasPrefix(actionRequest, "@") {
+		fn := strings.TrimPrefix(actionRequest, "@")
+		contents, err := ioutil.ReadFile(fn)
+		if err != nil {
+			fmt.Printf("can't read request file '%s': %s\n", fn, err)
+			os.Exit(exitFail)
+		}
+		actionRequest = strings.TrimSpace(string(contents))
+	}
+
 	// Both actionDFUPackage and actionRequest potentially use the 'remaining args' outside the flags
 	if actionDFUPackage != "" && actionRequest != "" {
 		fmt.Printf("-req and -binpack may not be combined into one command")
 		os.Exit(exitFail)
 	}
 
+	// Check a local firmware image, which doesn't require a notecard
+	if actionFirmwareSignCheck != "" {
+		err = firmwareSignCheck(actionFirmwareSignCheck)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(exitFail)
+		}
+		return
+	}
+
+	// Encode or decode COBS framing, which doesn't require a notecard
+	if actionCobsEncode != "" || actionCobsDecode != "" {
+		if actionCobsEncode != "" && actionCobsDecode != "" {
+			err = fmt.Errorf("-cobs-encode and -cobs-decode may not be combined")
+		} else if actionCobsEncode != "" {
+			err = cobsFile(actionCobsEncode, actionOutput, false)
+		} else {
+			err = cobsFile(actionCobsDecode, actionOutput, true)
+		}
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(exitFail)
+		}
+		return
+	}
+
+	// Probe a port without touching the configured notecard
+	if actionProbe != "" {
+		found, err := probe(actionProbe, actionPretty)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(exitFail)
+		}
+		if !found {
+			os.Exit(exitFail)
+		}
+		return
+	}
+
+	// Set up all attached notecards in parallel, each in its own process
+	if actionScanParallel != 0 {
+		var batchDelay time.Duration
+		if actionScan == "" {
+			err = fmt.Errorf("-scan-parallel requires -scan to specify the inventory file")
+		} else if actionBatchDelay != "" {
+			batchDelay, err = time.ParseDuration(actionBatchDelay)
+		}
+		if err == nil {
+			err = scanParallel(actionScan, actionScanParallel, batchDelay)
+		}
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(exitFail)
+		}
+		return
+	}
+
 	// Open the card, just to make sure errors are reported early
 	configVal := lib.Config.IPort[lib.Config.Interface].PortConfig
 	if actionPlaytime != 0 {
@@ -184,7 +411,20 @@ func main() {
 	}
 	notecard.InitialDebugMode = actionVerbose
 	notecard.InitialTraceMode = actionTrace
-	card, err = notecard.Open(lib.Config.Interface, lib.Config.IPort[lib.Config.Interface].Port, configVal)
+	err = cardOpen(lib.Config.Interface, lib.Config.IPort[lib.Config.Interface].Port, configVal)
+
+	// A leased notecard is reached over the network, so make sure that it is actually
+	// reachable before doing anything else with it
+	if err == nil && lib.Config.Interface == lib.ConfigInterfaceLease {
+		if lib.Config.IPort[lib.Config.Interface].Port == "" {
+			err = fmt.Errorf("please use -lease to specify the scope of notecards to lease")
+		} else {
+			_, err = cardTransactionRequest(notecard.Request{Req: "card.version"})
+			if err != nil {
+				err = fmt.Errorf("leased notecard in '%s' is not responding: %s", lib.Config.IPort[lib.Config.Interface].Port, err)
+			}
+		}
+	}
 
 	// Process non-config commands
 	var rsp notecard.Request
@@ -203,10 +443,37 @@ func main() {
 		notecard.RequestSegmentDelayMs = 5
 	}
 
+	// Allow precise tuning of the same parameters, overriding -fast
+	if err == nil && actionSegmentMax != 0 {
+		if actionSegmentMax < 16 || actionSegmentMax > 8192 {
+			err = fmt.Errorf("-segment-max must be between 16 and 8192 bytes")
+		} else {
+			notecard.RequestSegmentMaxLen = actionSegmentMax
+		}
+	}
+	if err == nil && actionSegmentDelay != -1 {
+		if actionSegmentDelay < 0 || actionSegmentDelay > 1000 {
+			err = fmt.Errorf("-segment-delay must be between 0 and 1000 milliseconds")
+		} else {
+			notecard.RequestSegmentDelayMs = actionSegmentDelay
+		}
+	}
+
+	// Bound the time spent waiting for the notecard's connection state
+	var waitDeadline time.Time
+	if actionSyncTimeout > 0 {
+		waitDeadline = time.Now().Add(time.Duration(actionSyncTimeout) * time.Minute)
+	}
+	waitTimedOut := false
+	waitExpired := func() bool {
+		waitTimedOut = !waitDeadline.IsZero() && time.Now().After(waitDeadline)
+		return waitTimedOut
+	}
+
 	// Wait until disconnected
 	if err == nil && actionWhenDisconnected {
 		for {
-			rsp, err := card.TransactionRequest(notecard.Request{Req: "hub.status", NotefileID: notecard.SyncLogNotefile, Delete: true})
+			rsp, err := cardTransactionRequest(notecard.Request{Req: "hub.status", NotefileID: notecard.SyncLogNotefile, Delete: true})
 			if err != nil {
 				fmt.Printf("%s\n", err)
 				break
@@ -215,15 +482,21 @@ func main() {
 				break
 			}
 			fmt.Printf("%s\n", rsp.Status)
+			if waitExpired() {
+				break
+			}
 			time.Sleep(3 * time.Second)
 		}
+		if waitTimedOut {
+			err = fmt.Errorf("notecard did not disconnect within %d minutes", actionSyncTimeout)
+		}
 	}
 
 	// Wait until connected
 	if err == nil && actionWhenConnected {
 		for {
 			delay := true
-			rsp, err := card.TransactionRequest(notecard.Request{Req: "note.get", NotefileID: notecard.SyncLogNotefile, Delete: true})
+			rsp, err := cardTransactionRequest(notecard.Request{Req: "note.get", NotefileID: notecard.SyncLogNotefile, Delete: true})
 			if err != nil && note.ErrorContains(err, note.ErrNoteNoExist) {
 				delay = true
 				err = nil
@@ -239,16 +512,22 @@ func main() {
 				note.BodyToObject(rsp.Body, &body)
 				fmt.Printf("%s\n", body.Text)
 			}
+			if waitExpired() {
+				break
+			}
 			if delay {
 				time.Sleep(3 * time.Second)
 			}
 		}
+		if waitTimedOut {
+			err = fmt.Errorf("notecard did not connect within %d minutes", actionSyncTimeout)
+		}
 	}
 
 	// Wait until disarmed
 	if err == nil && actionWhenDisarmed {
 		for {
-			rsp, err = card.TransactionRequest(notecard.Request{Req: "card.attn"})
+			rsp, err = cardTransactionRequest(notecard.Request{Req: "card.attn"})
 			if err != nil {
 				fmt.Printf("%s\n", err)
 			} else if rsp.Set {
@@ -262,9 +541,9 @@ func main() {
 	if err == nil && actionWhenSynced {
 		req := notecard.Request{Req: "hub.sync.status"}
 		req.Sync = true // Initiate sync if sync is needed
-		rsp, err = card.TransactionRequest(req)
+		rsp, err = cardTransactionRequest(req)
 		for err == nil {
-			rsp, err = card.TransactionRequest(notecard.Request{Req: "hub.sync.status"})
+			rsp, err = cardTransactionRequest(notecard.Request{Req: "hub.sync.status"})
 			if err != nil {
 				fmt.Printf("%s\n", err)
 				break
@@ -277,6 +556,10 @@ func main() {
 				break
 			}
 			fmt.Printf("%s\n", rsp.Status)
+			if waitExpired() {
+				err = fmt.Errorf("sync did not complete within %d minutes", actionSyncTimeout)
+				break
+			}
 			time.Sleep(3 * time.Second)
 		}
 	}
@@ -294,13 +577,13 @@ func main() {
 		if err == nil {
 			req := notecard.Request{Req: "card.setup"}
 			req.Text = requestsString
-			_, err = card.TransactionRequest(req)
+			_, err = cardTransactionRequest(req)
 		}
 		if err == nil && !(actionFactory || actionFormat) {
-			_, err = card.TransactionRequest(notecard.Request{Req: "card.restart"})
+			_, err = cardTransactionRequest(notecard.Request{Req: "card.restart"})
 			if err == nil {
 				for i := 0; i < 5; i++ {
-					_, err = card.TransactionRequest(notecard.Request{Req: "hub.get"})
+					_, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
 					if err == nil {
 						break
 					}
@@ -313,265 +596,65 @@ func main() {
 	verifyCompletion := false
 	if err == nil && actionFormat {
 		req := notecard.Request{Req: "card.restore"}
-		card.TransactionRequest(req)
+		cardTransactionRequest(req)
 		verifyCompletion = true
 	}
+	var keptEnv map[string]string
 	if err == nil && actionFactory && (actionScan == "" && actionSetup == "") {
-		req := notecard.Request{Req: "card.restore"}
-		req.Delete = true
-		_, err = card.TransactionRequest(req)
-		verifyCompletion = true
+		if actionKeepEnv {
+			keptEnv, err = envCapture()
+		}
+		if err == nil {
+			req := notecard.Request{Req: "card.restore"}
+			req.Delete = true
+			_, err = cardTransactionRequest(req)
+			verifyCompletion = true
+		}
 	}
 	if err == nil && verifyCompletion {
 		for i := 0; i < 5; i++ {
-			rsp, err = card.TransactionRequest(notecard.Request{Req: "hub.get"})
+			rsp, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
 			if err == nil {
 				break
 			}
 		}
 	}
+	if err == nil && keptEnv != nil {
+		err = envRestore(keptEnv)
+	}
 
 	if err == nil && actionInfo {
-
-		var infoErr error
 		if !actionVerbose {
 			card.DebugOutput(false, false)
 		}
+		err = infoShow(actionJSON, actionPretty, actionJSONSortKeys, actionOutput)
+	}
 
-		cardDeviceUID := ""
-		cardName := ""
-		cardSKU := ""
-		cardVersion := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.version"})
-		if err == nil {
-			cardDeviceUID = rsp.DeviceUID
-			cardName = rsp.Name
-			cardSKU = rsp.SKU
-			cardVersion = rsp.Version
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardICCID := ""
-		cardIMSI := ""
-		cardIMEI := ""
-		cardICCIDX := ""
-		cardIMSIX := ""
-		cardModem := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.wireless"})
-		if err == nil {
-			cardModem = rsp.Net.ModemFirmware
-			cardIMEI = rsp.Net.Imei
-			cardIMSI = rsp.Net.Imsi
-			cardICCID = rsp.Net.Iccid
-			cardIMSIX = rsp.Net.ImsiExternal
-			cardICCIDX = rsp.Net.IccidExternal
-		} else if !strings.Contains(err.Error(), "{not-supported}") {
-			infoErr = accumulateInfoErr(infoErr, err)
-		}
-
-		cardSN := ""
-		cardHost := ""
-		cardProductUID := ""
-		cardSyncMode := ""
-		OutboundPeriod := "-"
-		InboundPeriod := "-"
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "hub.get"})
-		if err == nil {
-			cardSN = rsp.SN
-			cardHost = rsp.Host
-			cardProductUID = rsp.ProductUID
-			cardSyncMode = rsp.Mode
-			if rsp.Minutes != 0 {
-				OutboundPeriod = fmt.Sprintf("%d minutes", rsp.Minutes)
-			}
-			if rsp.Outbound != 0 {
-				OutboundPeriod = fmt.Sprintf("%d minutes", rsp.Outbound)
-			}
-			if rsp.OutboundV != "" {
-				OutboundPeriod = rsp.OutboundV
-			}
-			if rsp.Hours != 0 {
-				InboundPeriod = fmt.Sprintf("%d hours", rsp.Hours)
-			}
-			if rsp.Inbound != 0 {
-				InboundPeriod = fmt.Sprintf("%d minutes", rsp.Inbound)
-			}
-			if rsp.InboundV != "" {
-				InboundPeriod = rsp.InboundV
-			}
-			if cardProductUID == "" {
-				cardProductUID = "*** Product UID is not set. Please use notehub.io to create a project and a product UID ***"
-			}
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardVoltage := 0.0
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.voltage"})
-		if err == nil {
-			cardVoltage = rsp.Value
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardTemp := 0.0
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.temp"})
-		if err == nil {
-			cardTemp = rsp.Value
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardGPSMode := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.location.mode"})
-		if err == nil {
-			if rsp.Status == "" {
-				cardGPSMode = rsp.Mode
-			} else {
-				cardGPSMode = rsp.Mode + " (" + rsp.Status + ")"
-			}
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardTime := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.time"})
-		if err == nil && rsp.Time > 0 {
-			cardTime = time.Unix(int64(rsp.Time), 0).Format("2006-01-02T15:04:05Z") + " (" +
-				time.Unix(int64(rsp.Time), 0).Local().Format("2006-01-02 3:04:05 PM MST") + ")"
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardLocation := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.location"})
-		if err == nil {
-			if rsp.Latitude != 0 || rsp.Longitude != 0 {
-				cardLocation = fmt.Sprintf("%f,%f (%s)", rsp.Latitude, rsp.Longitude, rsp.LocationOLC)
-			}
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardBootedTime := ""
-		cardStorageUsedPct := 0
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.status"})
-		if err == nil {
-			if rsp.Time > 0 {
-				cardBootedTime = time.Unix(int64(rsp.Time), 0).Format("2006-01-02T15:04:05Z") + " (" +
-					time.Unix(int64(rsp.Time), 0).Local().Format("2006-01-02 3:04:05 PM MST") + ")"
-			}
-			cardStorageUsedPct = int(rsp.Storage)
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardSyncedTime := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "hub.sync.status"})
-		if err == nil && rsp.Time > 0 {
-			cardSyncedTime = time.Unix(int64(rsp.Time), 0).Format("2006-01-02T15:04:05Z") + " (" +
-				time.Unix(int64(rsp.Time), 0).Local().Format("2006-01-02 3:04:05 PM MST") + ")"
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardServiceStatus := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "hub.status"})
-		if err == nil {
-			cardServiceStatus = rsp.Status
-			if rsp.Connected {
-				cardServiceStatus += " (connected)"
-			}
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardProvisionedTime := ""
-		cardUsedBytes := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.usage.get"})
-		if err == nil {
-			if rsp.Time > 0 {
-				cardProvisionedTime = time.Unix(int64(rsp.Time), 0).Format("2006-01-02T15:04:05Z") + " (" +
-					time.Unix(int64(rsp.Time), 0).Local().Format("2006-01-02 3:04:05 PM MST") + ")"
-			}
-			cardUsedBytes = fmt.Sprint(int(rsp.BytesSent + rsp.BytesReceived))
-		} else if strings.Contains(err.Error(), "{not-supported}") {
-			err = nil
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		cardEnv := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "env.get"})
-		if err == nil {
-			cardEnvBytes, _ := note.JSONMarshalIndent(rsp.Body, "                          ", "  ")
-			cardEnv = string(cardEnvBytes)
-			cardEnv = strings.TrimSuffix(cardEnv, "\n")
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
+	if err == nil && actionUsageReset {
+		err = usageReset()
+	}
 
-		cardNotefiles := ""
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "file.changes"})
-		if err == nil {
-			if rsp.FileInfo != nil {
-				for notefileID, info := range *rsp.FileInfo {
-					if cardNotefiles != "" {
-						cardNotefiles += ", "
-					}
-					if info.Changes > 0 {
-						cardNotefiles += fmt.Sprintf("%s (%d)", notefileID, info.Changes)
-					} else {
-						cardNotefiles += notefileID
-					}
-				}
-			}
-		}
-		infoErr = accumulateInfoErr(infoErr, err)
-
-		fmt.Printf("\n%s\n", cardName)
-		fmt.Printf("              ProductUID: %s\n", cardProductUID)
-		fmt.Printf("               DeviceUID: %s\n", cardDeviceUID)
-		fmt.Printf("           Serial Number: %s\n", cardSN)
-		fmt.Printf("            Notehub Host: %s\n", cardHost)
-		fmt.Printf("        Firmware Version: %s\n", cardVersion)
-		fmt.Printf("                     SKU: %s\n", cardSKU)
-		if cardModem != "" {
-			fmt.Printf("                   Modem: %s\n", cardModem)
-			fmt.Printf("                   ICCID: %s\n", cardICCID)
-			fmt.Printf("                    IMSI: %s\n", cardIMSI)
-			fmt.Printf("                    IMEI: %s\n", cardIMEI)
-		}
-		if cardICCIDX != "" {
-			fmt.Printf("          External ICCID: %s\n", cardICCIDX)
-			fmt.Printf("           External IMSI: %s\n", cardIMSIX)
-		}
-		if cardProvisionedTime != "" {
-			fmt.Printf("             Provisioned: %s\n", cardProvisionedTime)
-		}
-		if cardUsedBytes != "" {
-			fmt.Printf("       Used Over-the-Air: %s bytes\n", cardUsedBytes)
-		}
-		fmt.Printf("               Sync Mode: %s\n", cardSyncMode)
-		fmt.Printf("    Sync Outbound Period: %s\n", OutboundPeriod)
-		fmt.Printf("          Inbound Period: %s\n", InboundPeriod)
-		fmt.Printf("          Notehub Status: %s\n", cardServiceStatus)
-		fmt.Printf("             Last Synced: %s\n", cardSyncedTime)
-		fmt.Printf("                 Voltage: %0.02fV\n", cardVoltage)
-		fmt.Printf("             Temperature: %0.02fC\n", cardTemp)
-		fmt.Printf("                GPS Mode: %s\n", cardGPSMode)
-		fmt.Printf("                Location: %s\n", cardLocation)
-		fmt.Printf("            Current Time: %s\n", cardTime)
-		fmt.Printf("               Boot Time: %s\n", cardBootedTime)
-		fmt.Printf("               Notefiles: %s\n", cardNotefiles)
-		fmt.Printf("   Notefile Storage Used: %d%%\n", cardStorageUsedPct)
-		fmt.Printf("                     Env: %v\n", cardEnv)
-
-		err = infoErr
+	if err == nil && actionUsage {
+		err = usageShow()
 	}
 
 	if err == nil && actionProduct != "" {
-		_, err = card.TransactionRequest(notecard.Request{Req: "hub.set", ProductUID: actionProduct})
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", ProductUID: actionProduct})
 	}
 
 	if err == nil && actionSN != "" {
-		_, err = card.TransactionRequest(notecard.Request{Req: "hub.set", SN: actionSN})
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", SN: actionSN})
 	}
 
 	if err == nil && actionHub != "" {
-		_, err = card.TransactionRequest(notecard.Request{Req: "hub.set", Host: actionHub})
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", Host: actionHub})
 		lib.ConfigSetHub(actionHub)
 	}
 
+	if err == nil && actionDFUMode != "" {
+		err = dfuMode(actionDFUMode)
+	}
+
 	if err == nil && actionSideload != "" && actionScan == "" {
 		err = dfuSideload(actionSideload, actionVerbose)
 	}
@@ -581,9 +664,46 @@ func main() {
 		actionRequest = ""
 	}
 
+	if err == nil && actionReadout != "" {
+		err = readout(actionReadout, actionField, actionInterval)
+	}
+
+	if err == nil && actionReqFile != "" {
+		err = reqFile(actionReqFile, actionContinueOnError)
+	}
+
+	if err == nil && actionReqLoop {
+		err = reqLoop()
+	}
+
+	// Responses may be rendered through a template rather than as JSON
+	var outputTmpl *template.Template
+	if err == nil {
+		switch actionOutputFormat {
+		case "", "json":
+		case "template":
+			outputTmpl, err = outputTemplateParse(actionTemplate, actionTemplateFile)
+		default:
+			err = fmt.Errorf("-output-format must be json or template")
+		}
+	}
+
+	// Responses' payloads may be written to a sequence of files across all requests
+	var split *outputSplit
+	if actionOutputSplit != "" {
+		split = &outputSplit{prefix: actionOutputSplit}
+	}
+
 	if err == nil && actionRequest != "" {
+		if !payloadEncodingValid(actionPayloadEncoding) {
+			err = fmt.Errorf("-payload-encoding must be text, hex, base64, or raw")
+		} else if actionPayloadEncoding == "raw" && actionOutput == "" {
+			err = fmt.Errorf("-payload-encoding raw requires that an -output file be specified")
+		} else {
+			actionRequest, err = payloadRequestDecode(actionRequest, actionPayloadEncoding)
+		}
 		if err == nil {
-			var rspJSON []byte
+			var rspJSON, assertJSON []byte
 			var req, rsp notecard.Request
 			note.JSONUnmarshal([]byte(actionRequest), &req)
 
@@ -597,9 +717,13 @@ func main() {
 			}
 
 			// Perform the transaction and do special handling for binary
+			binaryMD5 := ""
 			if req.Req == "card.binary.get" {
 				expectedMD5 := req.Status
-				rsp, err = card.TransactionRequest(req)
+				err = firmwareRequire(req.Req, firmwareMinBinary)
+				if err == nil {
+					rsp, err = cardTransactionRequest(req)
+				}
 				if err == nil {
 					var rspBytes []byte
 					rspBytes, err = card.ReceiveBytes()
@@ -613,6 +737,7 @@ func main() {
 							} else {
 								rsp.Payload = &rspBytes
 								rsp.Cobs = 0
+								binaryMD5 = actualMD5
 							}
 						}
 					}
@@ -622,13 +747,13 @@ func main() {
 				actualMD5 := fmt.Sprintf("%x", md5.Sum(payload))
 				if req.Status != "" && !strings.EqualFold(req.Status, actualMD5) {
 					err = fmt.Errorf("actual MD5 %s != supplied 'status' field %s", actualMD5, req.Status)
-				} else {
+				} else if err = firmwareRequire(req.Req, firmwareMinBinary); err == nil {
 					req.Status = actualMD5
 					payload, err = notecard.CobsEncode(payload, byte('\n'))
 					if err == nil {
 						req.Payload = nil
 						req.Cobs = int32(len(payload))
-						rsp, err = card.TransactionRequest(req)
+						rsp, err = cardTransactionRequest(req)
 						if err == nil {
 							payload = append(payload, byte('\n'))
 							err = card.SendBytes(payload)
@@ -637,13 +762,27 @@ func main() {
 				}
 			} else {
 				actionRequest = strings.ReplaceAll(actionRequest, "\\n", "\n")
-				rspJSON, err = card.TransactionJSON([]byte(actionRequest))
+				rspJSON, err = cardTransactionJSON([]byte(actionRequest))
 				if err == nil {
 					_ = note.JSONUnmarshal(rspJSON, &rsp)
+					assertJSON = rspJSON
 				}
 			}
 
 			// Write the payload to an output file if appropriate
+			if err == nil && actionOutputPayloadOnly {
+				if actionOutput == "" {
+					err = fmt.Errorf("-output-payload-only requires that an -output file be specified")
+				} else if rsp.Payload == nil {
+					err = fmt.Errorf("response did not contain a payload")
+				}
+			}
+			if err == nil && actionOutputSplit != "" && rsp.Payload != nil {
+				_, err = split.write(*rsp.Payload)
+			}
+			if err == nil && actionOutputDir != "" && binaryMD5 != "" {
+				_, err = outputDirWrite(actionOutputDir, actionOutputNameTemplate, binaryMD5, *rsp.Payload)
+			}
 			if err == nil && actionOutput != "" {
 				if rsp.Payload != nil {
 					err = ioutil.WriteFile(actionOutput, *rsp.Payload, 0644)
@@ -654,38 +793,76 @@ func main() {
 			}
 
 			// Output the response to the console
-			if !actionVerbose {
-				if err == nil {
-					if actionPretty {
-						rspJSON, _ = note.JSONMarshalIndent(rsp, "", "    ")
-					} else {
-						rspJSON, _ = note.JSONMarshal(rsp)
+			if !actionVerbose && !actionOutputPayloadOnly {
+				if err == nil && outputTmpl != nil {
+					var out []byte
+					out, err = outputTemplate(outputTmpl, rsp)
+					if err == nil {
+						fmt.Printf("%s", out)
+					}
+				} else if err == nil {
+					var rspOut interface{}
+					rspOut, err = payloadResponseEncode(rsp, rsp.Payload, actionPayloadEncoding)
+					if err == nil {
+						rspJSON, _ = outputJSON(rspOut, actionPretty, actionJSONSortKeys)
+						fmt.Printf("%s\n", rspJSON)
 					}
-					fmt.Printf("%s\n", rspJSON)
 				}
 			}
+
+			// Fail if the response doesn't satisfy the assertions
+			if err == nil && len(actionAssert) > 0 {
+				if assertJSON == nil {
+					assertJSON, _ = note.JSONMarshal(rsp)
+				}
+				err = assertResponse(assertJSON, actionAssert)
+			}
 		}
 	}
 
 	if err == nil && actionLog != "" {
-		_, err = card.TransactionRequest(notecard.Request{Req: "hub.log", Text: actionLog})
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.log", Text: actionLog})
+	}
+
+	if err == nil && actionReconnect {
+		err = reconnect()
 	}
 
 	if err == nil && actionSync {
-		_, err = card.TransactionRequest(notecard.Request{Req: "hub.sync"})
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.sync"})
+	}
+
+	if err == nil && actionSyncCancel {
+		err = syncCancel()
+	}
+
+	if err == nil && actionSetupDump != "" {
+		err = setupDump(actionSetupDump, actionSetupDumpIdentity)
 	}
 
 	if err == nil && actionSetup != "" && actionScan == "" {
 		var requests []map[string]interface{}
 		requests, err = loadRequests(actionSetup)
+		var resume *setupResume
+		if err == nil && (actionSetupResume || actionSetupRestart) {
+			resume, err = setupResumeBegin(actionSetup, requests, actionSetupRestart)
+		}
 		if err == nil {
 			card.DebugOutput(true, false)
-			err = processRequests(actionFactory, requests)
+			var results []RequestResult
+			results, err = processRequests(actionFactory, requests, resume, split)
+			requestResultsSummary(results)
 		}
 	}
 
 	if err == nil && actionScan != "" {
-		err = scan(actionVerbose, actionFactory, actionSetup, actionSetupSKU, actionProvision, actionFactory, actionSideload, actionScan)
+		var batchDelay time.Duration
+		if actionBatchDelay != "" {
+			batchDelay, err = time.ParseDuration(actionBatchDelay)
+		}
+		if err == nil {
+			err = scan(actionVerbose, actionFactory, actionSetup, actionSetupSKU, actionProvision, actionFactory, actionSideload, actionScan, batchDelay)
+		}
 	}
 
 	if err == nil && actionCommtest {
@@ -694,14 +871,14 @@ func main() {
 		card.DebugOutput(false, false)
 
 		// Turn off tracing because it can interfere with our rapid transaction I/O
-		card.TransactionRequest(notecard.Request{Req: "card.io", Mode: "trace-off"})
+		cardTransactionRequest(notecard.Request{Req: "card.io", Mode: "trace-off"})
 
 		// Go into a high-frequency transaction loop
 		transactions := 0
 		began := time.Now()
 		lastMessage := time.Now()
 		for {
-			_, err = card.TransactionRequest(notecard.Request{Req: "card.version"})
+			_, err = cardTransactionRequest(notecard.Request{Req: "card.version"})
 			if err != nil {
 				break
 			}
@@ -713,8 +890,16 @@ func main() {
 		}
 	}
 
+	if err == nil && actionIOBenchmark != "" {
+		err = ioBenchmark(actionIOBenchmark, actionIOBenchmarkCount)
+	}
+
 	if err == nil && actionTrace {
-		err = card.Trace()
+		err = trace(actionTraceTimestamps, actionTraceColor)
+	}
+
+	if err == nil && actionWatchLevel != -1 {
+		err = watch(actionWatchLevel, actionWatchSeconds, actionWatchCount)
 	}
 
 	if err == nil && actionPlayground {
@@ -725,6 +910,68 @@ func main() {
 		err = echo(actionEcho)
 	}
 
+	if err == nil && actionFirmwareCheck != "" {
+		err = firmwareCheck(actionFirmwareCheck)
+	}
+
+	if err == nil && actionLocationSet != "" {
+		err = locationSet(actionLocationSet)
+	}
+
+	if err == nil && actionLocationMethod != "" {
+		err = locationMethod(actionLocationMethod)
+	}
+
+	if err == nil && len(actionEnvSet) > 0 {
+		err = envSet(actionEnvSet)
+	}
+
+	if err == nil && (actionEnvGet != "" || actionEnvGetAll) {
+		err = envGet(actionEnvGet)
+	}
+
+	if err == nil && (actionVoltageMode != "" || actionVoltageModeShow) {
+		err = powerVoltageMode(actionVoltageMode)
+	}
+
+	if err == nil && actionPower {
+		err = powerShow()
+	}
+
+	if err == nil && actionAttnGet {
+		err = attnGet(actionAttnPayload)
+	} else if err == nil && actionAttnSleep > 0 {
+		err = attnSleep(actionAttnSleep, actionAttnPayload)
+	} else if err == nil && actionAttnPayload != "" {
+		err = fmt.Errorf("-attn-payload must be used with -attn-sleep or -attn-get")
+	}
+
+	if err == nil && actionReboot {
+		err = reboot(actionRebootWait)
+	}
+
+	if err == nil && actionModemReset {
+		err = modemReset()
+	} else if err == nil && actionModemInfo {
+		err = modemInfo()
+	}
+
+	if err == nil && actionBinaryTest > 0 {
+		err = binaryTest(actionBinaryTest)
+	}
+
+	if err == nil && actionNotefileStats {
+		err = notefileStats()
+	}
+
+	if err == nil && actionWaitForVersion != "" {
+		err = firmwareWaitForVersion(actionWaitForVersion, actionWaitTimeout)
+	}
+
+	if err == nil && actionNTNStatus {
+		err = ntnStatus()
+	}
+
 	if err == nil && actionVersion {
 		fmt.Printf("Notecard CLI Version: %s\n", version)
 	}
@@ -0,0 +1,97 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blues/note-go/notecard"
+)
+
+// Fields of the "net" object of card.wireless, in display order
+var modemFields = []string{
+	"modem", "Modem Firmware",
+	"imei", "IMEI",
+	"iccid", "ICCID",
+	"iccid_external", "External ICCID",
+	"imsi", "IMSI",
+	"imsi_external", "External IMSI",
+	"apn", "APN",
+	"rat", "RAT",
+	"band", "Band",
+	"mcc", "MCC",
+	"mnc", "MNC",
+	"lac", "LAC",
+	"cid", "Cell ID",
+	"bars", "Bars",
+	"rssi", "RSSI",
+	"rsrp", "RSRP",
+	"rsrq", "RSRQ",
+	"sinr", "SINR",
+	"updated", "Updated",
+}
+
+// How long to wait for the notecard to come back after resetting the modem
+const modemResetTimeoutSecs = 60
+
+// Display the full set of modem diagnostics reported by card.wireless
+func modemInfo() (err error) {
+
+	rsp, err := cardTransactionMap(map[string]interface{}{"req": "card.wireless"})
+	if err != nil {
+		return
+	}
+
+	if status, present := rsp["status"]; present {
+		fmt.Printf("%24s: %v\n", "Registration State", status)
+	}
+	if mode, present := rsp["mode"]; present {
+		fmt.Printf("%24s: %v\n", "Mode", mode)
+	}
+	net, _ := rsp["net"].(map[string]interface{})
+	for i := 0; i < len(modemFields)/2; i++ {
+		key := modemFields[i*2]
+		label := modemFields[i*2+1]
+		v, present := net[key]
+		if !present {
+			continue
+		}
+		if key == "updated" {
+			fmt.Printf("%24s: %s\n", label, formatEpochTime(mapNumber(net, key)))
+		} else {
+			fmt.Printf("%24s: %v\n", label, v)
+		}
+	}
+	if len(net) == 0 {
+		fmt.Printf("the notecard did not report any modem information\n")
+	}
+
+	return
+
+}
+
+// Reset the modem by restarting the notecard, which power-cycles the modem, and then
+// wait for the notecard to return and show the modem's state
+func modemReset() (err error) {
+
+	fmt.Printf("resetting modem\n")
+	cardTransactionRequest(notecard.Request{Req: "card.restart"})
+
+	began := time.Now()
+	for {
+		time.Sleep(3 * time.Second)
+		_, err = cardTransactionRequest(notecard.Request{Req: "card.version"})
+		if err == nil {
+			break
+		}
+		if time.Since(began).Seconds() > modemResetTimeoutSecs {
+			return fmt.Errorf("notecard did not respond within %d seconds of resetting the modem: %s", modemResetTimeoutSecs, err)
+		}
+	}
+
+	return modemInfo()
+
+}
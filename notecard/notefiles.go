@@ -0,0 +1,80 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NotefileStats is the storage used by a single notefile
+type NotefileStats struct {
+	NotefileID string
+	Notes      int64
+	Changes    int64
+	Bytes      int64
+}
+
+// Display the number of notes in each notefile, with the largest first, so that it is
+// apparent which notefile is responsible for the notecard's storage being consumed
+func notefileStats() (err error) {
+
+	// Get the per-notefile info
+	rsp, err := cardTransactionMap(map[string]interface{}{"req": "file.changes"})
+	if err != nil {
+		return
+	}
+	stats := []NotefileStats{}
+	info, _ := rsp["info"].(map[string]interface{})
+	for notefileID, v := range info {
+		fileInfo, _ := v.(map[string]interface{})
+		stats = append(stats, NotefileStats{
+			NotefileID: notefileID,
+			Notes:      int64(mapNumber(fileInfo, "total")),
+			Changes:    int64(mapNumber(fileInfo, "changes")),
+			Bytes:      int64(mapNumber(fileInfo, "bytes")),
+		})
+	}
+
+	// Sort by size if the notecard reports it, else by number of notes
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		if stats[i].Notes != stats[j].Notes {
+			return stats[i].Notes > stats[j].Notes
+		}
+		return stats[i].NotefileID < stats[j].NotefileID
+	})
+
+	// Get the overall storage used
+	status, err := cardTransactionMap(map[string]interface{}{"req": "card.status"})
+	if err != nil {
+		return
+	}
+
+	// Display them
+	var totalNotes, totalChanges, totalBytes int64
+	fmt.Printf("%-32s %10s %10s %12s\n", "Notefile", "Notes", "Unsynced", "Bytes")
+	for _, s := range stats {
+		bytes := "-"
+		if s.Bytes > 0 {
+			bytes = fmt.Sprintf("%d", s.Bytes)
+		}
+		fmt.Printf("%-32s %10d %10d %12s\n", s.NotefileID, s.Notes, s.Changes, bytes)
+		totalNotes += s.Notes
+		totalChanges += s.Changes
+		totalBytes += s.Bytes
+	}
+	bytes := "-"
+	if totalBytes > 0 {
+		bytes = fmt.Sprintf("%d", totalBytes)
+	}
+	fmt.Printf("%-32s %10d %10d %12s\n", fmt.Sprintf("TOTAL (%d notefiles)", len(stats)), totalNotes, totalChanges, bytes)
+	fmt.Printf("\nNotefile Storage Used: %d%%\n", int64(mapNumber(status, "storage")))
+
+	return
+
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Documented satellite (NTN) status tokens and their interpretation; any others are
+// displayed as reported by the notecard
+var ntnStatusTokens = []string{
+	"{ntn-idle}", "idle, waiting for the next satellite session",
+}
+
+// Interpret all satellite status tokens found within a status string
+func ntnInterpretStatus(status string) (interpretation string) {
+	for i := 0; i < len(ntnStatusTokens)/2; i++ {
+		if strings.Contains(status, ntnStatusTokens[i*2]) {
+			if interpretation != "" {
+				interpretation += ", "
+			}
+			interpretation += ntnStatusTokens[i*2+1]
+		}
+	}
+	return
+}
+
+// Format a status string along with its interpretation, if any
+func ntnFormatStatus(status string) string {
+	if status == "" {
+		return "-"
+	}
+	interpretation := ntnInterpretStatus(status)
+	if interpretation == "" {
+		return status
+	}
+	return status + " (" + interpretation + ")"
+}
+
+// Display the satellite connectivity status of the Notecard
+func ntnStatus() (err error) {
+
+	// Find out which transport is currently being used
+	transport := "-"
+	rsp, err := cardTransactionMap(map[string]interface{}{"req": "card.transport"})
+	if err != nil && !strings.Contains(err.Error(), "{not-supported}") {
+		return
+	}
+	if err == nil {
+		method, _ := rsp["method"].(string)
+		if method != "" {
+			transport = method
+		}
+	}
+
+	// Get the status of the satellite module itself
+	rsp, err = cardTransactionMap(map[string]interface{}{"req": "ntn.status"})
+	if err != nil {
+		if strings.Contains(err.Error(), "{not-supported}") || strings.Contains(err.Error(), "unrecognized") {
+			return fmt.Errorf("this notecard does not support satellite (NTN) connectivity")
+		}
+		return
+	}
+	ntnState, _ := rsp["status"].(string)
+
+	// Get the notehub session status, which reflects satellite acquisition
+	rsp, err = cardTransactionMap(map[string]interface{}{"req": "hub.status"})
+	if err != nil {
+		return
+	}
+	hubState, _ := rsp["status"].(string)
+	if connected, _ := rsp["connected"].(bool); connected {
+		hubState += " (connected)"
+	}
+
+	// Get the state of syncing
+	rsp, err = cardTransactionMap(map[string]interface{}{"req": "hub.sync.status"})
+	if err != nil {
+		return
+	}
+	syncState, _ := rsp["status"].(string)
+	completed := int64(mapNumber(rsp, "completed"))
+
+	// Display the results
+	fmt.Printf("               Transport: %s\n", transport)
+	fmt.Printf("        Satellite Status: %s\n", ntnFormatStatus(ntnState))
+	fmt.Printf("          Notehub Status: %s\n", ntnFormatStatus(hubState))
+	fmt.Printf("             Sync Status: %s\n", ntnFormatStatus(syncState))
+	if completed > 0 {
+		fmt.Printf("        Last NTN Session: %d seconds ago\n", completed)
+	} else {
+		fmt.Printf("        Last NTN Session: -\n")
+	}
+	fmt.Printf("   Next Satellite Passes: not available from the notecard\n")
+
+	return
+
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/blues/note-go/note"
+)
+
+// Marshal an object for output, optionally indented and optionally with all object keys sorted.
+// Keys are sorted by round-tripping through generic maps, whose keys are always marshaled in
+// sorted order, so that the output is deterministic regardless of how the object was built.
+func outputJSON(obj interface{}, pretty bool, sortKeys bool) (objJSON []byte, err error) {
+	if sortKeys {
+		objJSON, err = note.JSONMarshal(obj)
+		if err != nil {
+			return
+		}
+		var generic interface{}
+		err = note.JSONUnmarshal(objJSON, &generic)
+		if err != nil {
+			return
+		}
+		obj = generic
+	}
+	if pretty {
+		return note.JSONMarshalIndent(obj, "", "    ")
+	}
+	return note.JSONMarshal(obj)
+}
+
+// A sequence of numbered files to which successive response payloads are written
+type outputSplit struct {
+	prefix string
+	count  int
+}
+
+// Write a response payload to the next file in the sequence, returning its name
+func (s *outputSplit) write(payload []byte) (filename string, err error) {
+	if s == nil {
+		return
+	}
+	s.count++
+	filename = fmt.Sprintf("%s-%04d.bin", s.prefix, s.count)
+	err = ioutil.WriteFile(filename, payload, 0644)
+	return
+}
+
+// Default name for payloads written to an -output-dir
+const outputDirNameDefault = "{{.MD5}}.bin"
+
+// Fields available to -output-name-template
+type outputDirName struct {
+	MD5    string
+	Length int
+	Time   int64
+}
+
+// Write a binary payload into a directory, named by a template that defaults to its MD5
+func outputDirWrite(dir string, nameTemplate string, md5 string, payload []byte) (filename string, err error) {
+	if nameTemplate == "" {
+		nameTemplate = outputDirNameDefault
+	}
+	tmpl, err := template.New("name").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("-output-name-template: %s", err)
+	}
+	var name bytes.Buffer
+	err = tmpl.Execute(&name, outputDirName{MD5: md5, Length: len(payload), Time: time.Now().Unix()})
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return
+	}
+	filename = filepath.Join(dir, name.String())
+	err = ioutil.WriteFile(filename, payload, 0644)
+	return
+}
+
+// Functions available to -output-format template
+var outputTemplateFuncs = template.FuncMap{
+	"time": func(secs interface{}) string {
+		return time.Unix(outputTemplateSecs(secs), 0).UTC().Format("2006-01-02T15:04:05Z")
+	},
+	"localtime": func(secs interface{}) string {
+		return time.Unix(outputTemplateSecs(secs), 0).Local().Format("2006-01-02 3:04:05 PM MST")
+	},
+	"json": func(v interface{}) string {
+		vJSON, _ := note.JSONMarshal(v)
+		return string(vJSON)
+	},
+}
+
+// Convert any numeric template argument to epoch seconds
+func outputTemplateSecs(secs interface{}) int64 {
+	switch v := secs.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// Parse the template for -output-format template, from either the text or a file
+func outputTemplateParse(text string, filename string) (tmpl *template.Template, err error) {
+	if filename != "" {
+		var contents []byte
+		contents, err = ioutil.ReadFile(filename)
+		if err != nil {
+			return
+		}
+		text = string(contents)
+	}
+	if text == "" {
+		return nil, fmt.Errorf("-output-format template requires -template or -template-file")
+	}
+	return template.New("output").Funcs(outputTemplateFuncs).Parse(text)
+}
+
+// Render an object through an output template, ending with a newline
+func outputTemplate(tmpl *template.Template, obj interface{}) (out []byte, err error) {
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, obj)
+	if err != nil {
+		return
+	}
+	out = buf.Bytes()
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/blues/note-go/note"
+)
+
+// Validate a -payload-encoding, where "" is the notecard's native base64
+func payloadEncodingValid(encoding string) bool {
+	switch encoding {
+	case "", "text", "hex", "base64", "raw":
+		return true
+	}
+	return false
+}
+
+// Convert the payload of a request from the specified encoding to the base64 that the
+// notecard expects, leaving the request as-is if it has no payload or is already base64
+func payloadRequestDecode(reqJSON string, encoding string) (string, error) {
+	if encoding == "" || encoding == "base64" || encoding == "raw" {
+		return reqJSON, nil
+	}
+
+	req := map[string]interface{}{}
+	err := note.JSONUnmarshal([]byte(reqJSON), &req)
+	if err != nil {
+		return reqJSON, err
+	}
+	payload, isString := req["payload"].(string)
+	if !isString {
+		return reqJSON, nil
+	}
+
+	var data []byte
+	switch encoding {
+	case "text":
+		data = []byte(payload)
+	case "hex":
+		data, err = hex.DecodeString(strings.ReplaceAll(payload, " ", ""))
+		if err != nil {
+			return reqJSON, fmt.Errorf("payload is not valid hex: %s", err)
+		}
+	}
+	req["payload"] = base64.StdEncoding.EncodeToString(data)
+
+	updatedJSON, err := note.JSONMarshal(req)
+	return string(updatedJSON), err
+}
+
+// Render a response for the console with its payload in the specified encoding.  For raw,
+// the payload is left out of the console entirely because it is written to -output.
+func payloadResponseEncode(rsp interface{}, payload *[]byte, encoding string) (interface{}, error) {
+	if encoding == "" || encoding == "base64" || payload == nil {
+		return rsp, nil
+	}
+
+	rspJSON, err := note.JSONMarshal(rsp)
+	if err != nil {
+		return rsp, err
+	}
+	generic := map[string]interface{}{}
+	err = note.JSONUnmarshal(rspJSON, &generic)
+	if err != nil {
+		return rsp, err
+	}
+
+	switch encoding {
+	case "text":
+		generic["payload"] = string(*payload)
+	case "hex":
+		generic["payload"] = hex.EncodeToString(*payload)
+	case "raw":
+		delete(generic, "payload")
+	}
+
+	return generic, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// How many hours of voltage history to summarize
+const powerHistoryHours = 24
+
+// Display a consolidated view of the notecard's power supply
+func powerShow() (err error) {
+
+	// Voltage now and over the history period, if the notecard tracks it
+	voltage, err := cardTransactionMap(map[string]interface{}{"req": "card.voltage", "hours": powerHistoryHours})
+	if err != nil {
+		return
+	}
+	status, err := cardTransactionMap(map[string]interface{}{"req": "card.status"})
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("%24s: %.2fV\n", "Voltage", mapNumber(voltage, "value"))
+	if mode, _ := voltage["mode"].(string); mode != "" {
+		fmt.Printf("%24s: %s\n", "Voltage Mode", mode)
+	}
+	usb, _ := status["usb"].(bool)
+	if !usb {
+		usb, _ = voltage["usb"].(bool)
+	}
+	if usb {
+		fmt.Printf("%24s: %s\n", "Power Source", "USB")
+	} else {
+		fmt.Printf("%24s: %s\n", "Power Source", "battery or external supply")
+	}
+
+	// History and trend
+	if _, present := voltage["vmin"]; present {
+		hours := int64(mapNumber(voltage, "hours"))
+		if hours == 0 {
+			hours = powerHistoryHours
+		}
+		fmt.Printf("%24s: %.2fV min, %.2fV avg, %.2fV max\n", fmt.Sprintf("Last %d Hours", hours),
+			mapNumber(voltage, "vmin"), mapNumber(voltage, "vavg"), mapNumber(voltage, "vmax"))
+	}
+	trends := []string{"daily", "Daily Trend", "weekly", "Weekly Trend", "monthly", "Monthly Trend"}
+	for i := 0; i < len(trends)/2; i++ {
+		if _, present := voltage[trends[i*2]]; !present {
+			continue
+		}
+		trend := mapNumber(voltage, trends[i*2])
+		direction := "steady"
+		if trend > 0 {
+			direction = "charging"
+		} else if trend < 0 {
+			direction = "discharging"
+		}
+		fmt.Printf("%24s: %+.2fV (%s)\n", trends[i*2+1], trend, direction)
+	}
+
+	return
+
+}
+
+// Voltage profiles built into the notecard
+var powerVoltageModes = []string{"default", "lipo", "l91", "alkaline", "tad", "lic"}
+
+// Validate a voltage mode, which is either a built-in profile or a custom profile of
+// semicolon-separated level:volts thresholds such as usb:4.6;high:4.0;normal:3.5;low:3.2;dead:0
+func powerVoltageModeValid(mode string) error {
+	for _, m := range powerVoltageModes {
+		if mode == m {
+			return nil
+		}
+	}
+	if !strings.Contains(mode, ":") {
+		return fmt.Errorf("voltage mode must be one of %s, or custom thresholds such as usb:4.6;high:4.0;normal:3.5;low:3.2;dead:0", strings.Join(powerVoltageModes, ", "))
+	}
+	for _, threshold := range strings.Split(mode, ";") {
+		kv := strings.SplitN(threshold, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("voltage threshold '%s' must be in the form level:volts", threshold)
+		}
+		if _, err := strconv.ParseFloat(kv[1], 64); err != nil {
+			return fmt.Errorf("voltage threshold '%s' has an invalid voltage", threshold)
+		}
+	}
+	return nil
+}
+
+// Set the notecard's voltage profile, if specified, and show the resulting configuration
+func powerVoltageMode(mode string) (err error) {
+
+	if mode != "" {
+		err = powerVoltageModeValid(mode)
+		if err != nil {
+			return
+		}
+		_, err = cardTransactionMap(map[string]interface{}{"req": "card.voltage", "mode": mode})
+		if err != nil {
+			return
+		}
+	}
+
+	voltage, err := cardTransactionMap(map[string]interface{}{"req": "card.voltage"})
+	if err != nil {
+		return
+	}
+	current, _ := voltage["mode"].(string)
+	if current == "" {
+		current = "default"
+	}
+	fmt.Printf("%24s: %s\n", "Voltage Mode", current)
+	fmt.Printf("%24s: %.2fV\n", "Voltage", mapNumber(voltage, "value"))
+	if mode != "" && mode != current && !strings.Contains(mode, ":") {
+		return fmt.Errorf("notecard reports voltage mode '%s' rather than '%s'", current, mode)
+	}
+
+	return
+
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notecard"
+)
+
+// ProbeResult describes what was found on a port
+type ProbeResult struct {
+	Interface string `json:"interface,omitempty"`
+	Port      string `json:"port,omitempty"`
+	Notecard  bool   `json:"notecard"`
+	DeviceUID string `json:"device,omitempty"`
+	Name      string `json:"name,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Err       string `json:"err,omitempty"`
+}
+
+// Non-destructively identify what is on a port by opening it, issuing a single card.version,
+// and closing it, without applying or saving any configuration
+func probe(port string, pretty bool) (found bool, err error) {
+
+	result := ProbeResult{Port: port}
+	result.Interface = lib.Config.Interface
+	if result.Interface == "" {
+		result.Interface, _, _ = notecard.Defaults()
+	}
+	portConfig := lib.Config.IPort[result.Interface].PortConfig
+
+	context, err2 := notecard.Open(result.Interface, port, portConfig)
+	if err2 != nil {
+		result.Err = err2.Error()
+	} else {
+		context.DebugOutput(false, false)
+		rsp, err2 := context.TransactionRequest(notecard.Request{Req: "card.version"})
+		context.Close()
+		if err2 != nil {
+			result.Err = fmt.Sprintf("not a notecard: %s", err2)
+		} else if rsp.DeviceUID == "" {
+			result.Err = "not a notecard: no DeviceUID in response"
+		} else {
+			result.Notecard = true
+			result.DeviceUID = rsp.DeviceUID
+			result.Name = rsp.Name
+			result.SKU = rsp.SKU
+			result.Version = rsp.Version
+		}
+	}
+
+	var resultJSON []byte
+	if pretty {
+		resultJSON, err = note.JSONMarshalIndent(result, "", "    ")
+	} else {
+		resultJSON, err = note.JSONMarshal(result)
+	}
+	if err == nil {
+		fmt.Printf("%s\n", resultJSON)
+	}
+
+	found = result.Notecard
+	return
+
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/blues/note-go/note"
+)
+
+// Characters used to draw a sparkline, from lowest to highest
+var readoutSparks = []rune("▁▂▃▄▅▆▇█")
+
+// How many recent values are shown in the sparkline
+const readoutSparkWidth = 40
+
+// Draw recent values as a sparkline scaled between their minimum and maximum
+func readoutSparkline(values []float64) string {
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	line := []rune{}
+	for _, v := range values {
+		i := 0
+		if max > min {
+			i = int((v - min) / (max - min) * float64(len(readoutSparks)-1))
+		}
+		line = append(line, readoutSparks[i])
+	}
+	return string(line)
+}
+
+// Repeatedly issue a request and print the value of one field of its response, with a
+// sparkline of recent values when the output is a terminal, until interrupted
+func readout(request string, field string, interval string) (err error) {
+
+	if field == "" {
+		return fmt.Errorf("use -field to specify the response field to show, such as value or body.temp")
+	}
+	every := 2 * time.Second
+	if interval != "" {
+		every, err = time.ParseDuration(interval)
+		if err != nil {
+			return
+		}
+	}
+	fi, _ := os.Stdout.Stat()
+	tty := fi != nil && (fi.Mode()&os.ModeCharDevice) != 0
+
+	card.DebugOutput(false, false)
+	values := []float64{}
+	for {
+
+		var rspJSON []byte
+		rspJSON, err = cardTransactionJSON([]byte(request))
+		if err != nil {
+			return
+		}
+		rsp := map[string]interface{}{}
+		err = note.JSONUnmarshal(rspJSON, &rsp)
+		if err != nil {
+			return
+		}
+		now := time.Now().Format("15:04:05")
+		value, present := conditionLookup(rsp, field)
+		if !present {
+			fmt.Printf("%s %s: (not present)\n", now, field)
+		} else {
+			s := conditionString(value)
+			n, err2 := strconv.ParseFloat(s, 64)
+			if tty && err2 == nil {
+				values = append(values, n)
+				if len(values) > readoutSparkWidth {
+					values = values[1:]
+				}
+				fmt.Printf("%s %s: %-12s %s\n", now, field, s, readoutSparkline(values))
+			} else {
+				fmt.Printf("%s %s: %s\n", now, field, s)
+			}
+		}
+
+		time.Sleep(every)
+
+	}
+
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blues/note-go/notecard"
+)
+
+// How long -reboot-wait waits for the notecard to respond after restarting
+const rebootTimeoutSecs = 60
+
+// Restart the notecard and, if requested, wait for it to respond again and show the
+// firmware version that it came back with
+func reboot(wait bool) (err error) {
+
+	// When waiting, re-open the port if it re-enumerates, just as -serial-reconnect-on-reset does
+	if wait {
+		cardReconnectOnReset = true
+	}
+
+	fmt.Printf("restarting notecard\n")
+	cardTransactionRequest(notecard.Request{Req: "card.restart"})
+	if !wait {
+		return
+	}
+
+	began := time.Now()
+	var rsp map[string]interface{}
+	for {
+		time.Sleep(3 * time.Second)
+		_, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
+		if err == nil {
+			rsp, err = cardTransactionMap(map[string]interface{}{"req": "card.version"})
+		}
+		if err == nil {
+			break
+		}
+		if time.Since(began).Seconds() > rebootTimeoutSecs {
+			return fmt.Errorf("notecard did not respond within %d seconds of restarting: %s", rebootTimeoutSecs, err)
+		}
+	}
+
+	version, _ := rsp["version"].(string)
+	fmt.Printf("notecard restarted after %.0f seconds running %s\n", time.Since(began).Seconds(), version)
+
+	return
+
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blues/note-go/notecard"
+)
+
+// How long to wait for the notecard to reconnect after cycling the radio
+const reconnectTimeoutSecs = 180
+
+// Get a printable form of the notehub connection status
+func reconnectStatus() (status string, connected bool, err error) {
+	var rsp notecard.Request
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "hub.status"})
+	if err != nil {
+		return
+	}
+	status = rsp.Status
+	connected = rsp.Connected
+	if connected {
+		status += " (connected)"
+	}
+	return
+}
+
+// Recover connectivity by turning the radio off and back on, then syncing and waiting
+// until the notecard reports that it is connected to notehub
+func reconnect() (err error) {
+
+	// Show the state before we begin
+	var status string
+	status, _, err = reconnectStatus()
+	if err != nil {
+		return
+	}
+	fmt.Printf("before: %s\n", status)
+
+	// Remember the current mode so that we can restore it
+	var rsp notecard.Request
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
+	if err != nil {
+		return
+	}
+	mode := rsp.Mode
+	if mode == "" || mode == "off" {
+		mode = "periodic"
+	}
+
+	// Cycle the radio
+	fmt.Printf("turning radio off\n")
+	_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", Mode: "off"})
+	if err != nil {
+		return
+	}
+	time.Sleep(5 * time.Second)
+	fmt.Printf("turning radio back on in '%s' mode\n", mode)
+	_, err = cardTransactionRequest(notecard.Request{Req: "hub.set", Mode: mode})
+	if err != nil {
+		return
+	}
+	_, err = cardTransactionRequest(notecard.Request{Req: "hub.sync"})
+	if err != nil {
+		return
+	}
+
+	// Wait until connected
+	connected := false
+	began := time.Now()
+	for time.Since(began).Seconds() < reconnectTimeoutSecs {
+		status, connected, err = reconnectStatus()
+		if err != nil {
+			return
+		}
+		if connected {
+			break
+		}
+		fmt.Printf("%s\n", status)
+		time.Sleep(3 * time.Second)
+	}
+
+	// Show the state after we're done
+	fmt.Printf("after: %s\n", status)
+	if !connected {
+		err = fmt.Errorf("notecard did not reconnect within %d seconds", reconnectTimeoutSecs)
+	}
+
+	return
+
+}
@@ -16,6 +16,7 @@ import (
 	"os/user"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,12 +27,46 @@ import (
 	"github.com/peterh/liner"
 )
 
+// Request names offered by tab completion within "req":"..."
+var replRequestNames = []string{
+	"card.attn", "card.aux", "card.binary", "card.binary.get", "card.binary.put", "card.checkpoint",
+	"card.dfu", "card.io", "card.location", "card.location.mode", "card.location.track", "card.motion",
+	"card.motion.mode", "card.restart", "card.restore", "card.status", "card.temp", "card.time",
+	"card.transport", "card.triangulate", "card.usage.get", "card.version", "card.voltage", "card.wifi",
+	"card.wireless", "dfu.get", "dfu.put", "dfu.status", "env.default", "env.get", "env.set",
+	"file.changes", "file.delete", "file.stats", "hub.get", "hub.log", "hub.set", "hub.status",
+	"hub.sync", "hub.sync.status", "note.add", "note.changes", "note.delete", "note.get", "note.template",
+	"note.update", "ntn.status", "web.get", "web.post", "web.put",
+}
+
+// Matches a line ending within the value of a "req" field
+var replRequestPattern = regexp.MustCompile(`"(req|cmd)"\s*:\s*"([a-z0-9._-]*)$`)
+
+// Complete a partially-typed request name
+func replCompleter(line string) (completions []string) {
+	match := replRequestPattern.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	prefix := line[:len(line)-len(match[2])]
+	for _, name := range replRequestNames {
+		if strings.HasPrefix(name, match[2]) {
+			completions = append(completions, prefix+name+"\"")
+		}
+	}
+	return
+}
+
 type REPL struct {
 	context         *notecard.Context
 	historyFilePath string
 	liner           *liner.State
 	format          bool
 	watcher         *Watcher
+	macrosFilePath  string
+	macros          map[string]string
+	lastCommand     string
+	watchFilter     string
 }
 
 func NewREPL(context *notecard.Context) *REPL {
@@ -42,13 +77,23 @@ func NewREPL(context *notecard.Context) *REPL {
 		liner:           liner.NewLiner(),
 		format:          true,
 		watcher:         nil,
+		macrosFilePath:  path.Join(usr.HomeDir, ".notecard-macros.json"),
+		macros:          map[string]string{},
 	}
 
+	repl.liner.SetCompleter(replCompleter)
+
 	if f, err := os.Open(repl.historyFilePath); err == nil {
 		repl.liner.ReadHistory(f)
 		f.Close()
 	}
 
+	if contents, err := ioutil.ReadFile(repl.macrosFilePath); err == nil {
+		if err = json.Unmarshal(contents, &repl.macros); err != nil {
+			fmt.Println("error reading macros file: ", err)
+		}
+	}
+
 	return repl
 }
 
@@ -69,8 +114,15 @@ watch             Print real time notecard activity (CTRL-C to stop)
 watch [on|off]    If enabled, Notecard activity will be collected in the
                   background.  It can be viewed with the 'watch' command
                   (default: off)
+watch grep <text> Only show watched activity containing the text
+watch grep off    Show all watched activity
 format [on|off]   Auto-format JSON responses (default: on)
+send <file>       Send the JSON request contained in a file
+load <file.json>  Send each of the newline-delimited requests in a file
 history           Show command history
+macro save <name> Save the previous request as a macro
+macro list        Show the saved macros
+macro <name>      Run a saved macro
 quit              Exit out of the REPL (CTRL-D also exits)`
 }
 
@@ -83,6 +135,13 @@ func (repl *REPL) writeHistory() {
 	}
 }
 
+func (repl *REPL) writeMacros() {
+	contents, _ := json.MarshalIndent(repl.macros, "", "    ")
+	if err := ioutil.WriteFile(repl.macrosFilePath, contents, 0644); err != nil {
+		fmt.Println("error writing macros file: ", err)
+	}
+}
+
 func (repl *REPL) close() {
 	repl.writeHistory()
 	repl.liner.Close()
@@ -108,6 +167,47 @@ repl:
 			normalized := strings.Trim(strings.ToLower(input), " \t")
 			normalized = regexp.MustCompile(`\s+`).ReplaceAllString(normalized, " ")
 
+			if strings.HasPrefix(normalized, "macro ") {
+				args := strings.Fields(normalized)
+				switch {
+				case len(args) == 3 && args[1] == "save":
+					if repl.lastCommand == "" {
+						fmt.Printf("there is no previous request to save\n")
+						continue repl
+					}
+					repl.macros[args[2]] = repl.lastCommand
+					repl.writeMacros()
+					fmt.Printf("macro %s saved: %s\n", args[2], repl.lastCommand)
+					continue repl
+				case len(args) == 2 && args[1] == "list":
+					if len(repl.macros) == 0 {
+						fmt.Printf("no macros have been saved\n")
+					}
+					names := []string{}
+					for name := range repl.macros {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					for _, name := range names {
+						fmt.Printf("%-16s %s\n", name, repl.macros[name])
+					}
+					continue repl
+				case len(args) == 2:
+					macro, present := repl.macros[args[1]]
+					if !present {
+						fmt.Printf("no macro named %s\n", args[1])
+						continue repl
+					}
+					fmt.Printf(">>> %s\n", macro)
+					input = macro
+					normalized = strings.Trim(strings.ToLower(input), " \t")
+					normalized = regexp.MustCompile(`\s+`).ReplaceAllString(normalized, " ")
+				default:
+					fmt.Printf("usage: macro save <name>, macro list, or macro <name>\n")
+					continue repl
+				}
+			}
+
 			switch normalized {
 			case "quit":
 				fallthrough
@@ -148,6 +248,10 @@ repl:
 				repl.watcher.Stop()
 				repl.watcher = nil
 				continue repl
+			case "watch grep off":
+				fmt.Printf("watch filter off\n")
+				repl.watchFilter = ""
+				continue repl
 			case "watch":
 				if repl.watcher == nil {
 					fmt.Printf("watch mode is off, use 'watch on' to start\n")
@@ -164,32 +268,54 @@ repl:
 						signal.Reset(os.Interrupt)
 						continue repl
 					case log := <-logsChan:
-						fmt.Printf("%s\n", log)
+						if repl.watchFilter == "" || strings.Contains(log, repl.watchFilter) {
+							fmt.Printf("%s\n", log)
+						}
 					}
 				}
 			}
 
-			if isJsonObject(input) {
-				// Run the command and print out response
-				rspJSON, err := repl.context.TransactionJSON([]byte(input))
+			if strings.HasPrefix(normalized, "watch grep ") {
+				repl.watchFilter = strings.TrimSpace(strings.TrimSpace(input)[len("watch grep "):])
+				fmt.Printf("watch filter: %s\n", repl.watchFilter)
+				continue repl
+			}
+
+			if strings.HasPrefix(normalized, "send ") {
+				filename := strings.TrimSpace(strings.TrimSpace(input)[len("send "):])
+				contents, err := ioutil.ReadFile(filename)
 				if err != nil {
 					fmt.Printf("error: %s\n", err)
-					continue repl
+				} else if !isJsonObject(string(contents)) {
+					fmt.Printf("error: %s does not contain a JSON request\n", filename)
+				} else {
+					repl.lastCommand = input
+					repl.transaction(contents)
 				}
+				continue repl
+			}
 
-				response := string(rspJSON)
-				if repl.format {
-					var raw map[string]interface{}
-					err := json.Unmarshal(rspJSON, &raw)
-					if err == nil {
-						formatted, err := json.MarshalIndent(raw, "", "    ")
-						if err == nil {
-							response = string(formatted) + "\n"
-						}
+			if strings.HasPrefix(normalized, "load ") {
+				filename := strings.TrimSpace(strings.TrimSpace(input)[len("load "):])
+				requests, err := loadRequests(filename)
+				if err != nil {
+					fmt.Printf("error: %s\n", err)
+					continue repl
+				}
+				repl.lastCommand = input
+				for _, req := range requests {
+					reqJSON, _ := note.JSONMarshal(req)
+					fmt.Printf(">>> %s\n", reqJSON)
+					if !repl.transaction(reqJSON) {
+						break
 					}
 				}
+				continue repl
+			}
 
-				fmt.Printf("%s", string(response))
+			if isJsonObject(input) {
+				repl.lastCommand = input
+				repl.transaction([]byte(input))
 				continue repl
 			}
 
@@ -206,6 +332,32 @@ repl:
 	return 0
 }
 
+// Run a request and print out its response, returning false if it failed
+func (repl *REPL) transaction(reqJSON []byte) bool {
+	// Go through the same path as the CLI so that the port is re-opened after a restart
+	rspJSON, err := cardTransactionJSON(reqJSON)
+	repl.context = card
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return false
+	}
+
+	response := string(rspJSON)
+	if repl.format {
+		var raw map[string]interface{}
+		err := json.Unmarshal(rspJSON, &raw)
+		if err == nil {
+			formatted, err := json.MarshalIndent(raw, "", "    ")
+			if err == nil {
+				response = string(formatted) + "\n"
+			}
+		}
+	}
+
+	fmt.Printf("%s", string(response))
+	return true
+}
+
 type WatchLogLine struct {
 	date      *time.Time
 	subsystem string
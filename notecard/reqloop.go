@@ -0,0 +1,100 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/blues/note-go/note"
+)
+
+// The largest request line accepted on stdin
+const reqLoopMaxLine = 1024 * 1024
+
+// Read one JSON request per line from stdin, writing exactly one JSON response per line to
+// stdout as soon as each transaction completes, until stdin is closed.  Nothing else is written
+// to stdout so that a controlling process can treat it as a request/response protocol.
+func reqLoop() (err error) {
+
+	card.DebugOutput(false, false)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), reqLoopMaxLine)
+	for scanner.Scan() {
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		rspJSON, err2 := cardTransactionJSON(line)
+		if err2 != nil {
+			rspJSON, _ = note.JSONMarshal(map[string]interface{}{"err": err2.Error()})
+		}
+		rspJSON = bytes.TrimSpace(rspJSON)
+
+		// Stdout is unbuffered, so each response is delivered as a single write
+		_, err = os.Stdout.Write(append(rspJSON, '\n'))
+		if err != nil {
+			return
+		}
+
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		err = fmt.Errorf("reading requests: %s", err)
+	}
+
+	return
+
+}
+
+// Send each JSON request in a newline-delimited file exactly as written, printing each response
+// as it was received.  Unless continuing on error, the first transport error stops processing.
+func reqFile(filename string, continueOnError bool) (err error) {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	failed := 0
+	lineNumber := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), reqLoopMaxLine)
+	for scanner.Scan() {
+
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		rspJSON, err2 := cardTransactionJSON(line)
+		if err2 != nil {
+			failed++
+			if !continueOnError {
+				return fmt.Errorf("%s:%d: %s", filename, lineNumber, err2)
+			}
+			fmt.Printf("%s:%d: %s\n", filename, lineNumber, err2)
+			continue
+		}
+		fmt.Printf("%s\n", bytes.TrimSpace(rspJSON))
+
+	}
+
+	err = scanner.Err()
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d requests in %s failed", failed, filename)
+	}
+
+	return
+
+}
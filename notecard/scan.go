@@ -42,8 +42,11 @@ type ScannedSIM struct {
 	Key   string `json:"key,omitempty"`
 }
 
+// Set by -scan-once to stop scanning after a single notecard has been processed
+var scanOnce bool
+
 // Scan of a set of notecards, appending to JSON file.  Press ^C when done.
-func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carrierProvision string, factoryReset bool, sideload string, outfile string) (err error) {
+func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carrierProvision string, factoryReset bool, sideload string, outfile string, batchDelay time.Duration) (err error) {
 
 	// Only allow one of the two
 	if fnSetup != "" && fnSetupSKU != "" {
@@ -80,60 +83,19 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 	// Generate a SIM file with a CSV extension
 	simfile := strings.TrimSuffix(outfile, ".json") + ".csv"
 
-	// Start the input handler
-	go inputHandler()
+	// Start the input handler, unless we're being run for a single notecard
+	if !scanOnce {
+		go inputHandler()
+	}
 
 	// Turn off debug output
 	card.DebugOutput(debugEnabled, false)
 
-	// Read the file into an array that we'll keep ordered
-	var contents []byte
-	var scannedDevices []ScannedDevice
-	contents, err = ioutil.ReadFile(outfile)
-	if err != nil {
-		fmt.Printf("*** new file: %s\n", outfile)
-	} else {
-		jrecs := bytes.Split(contents, []byte("\n"))
-		for _, line := range jrecs {
-			if len(line) == 0 {
-				continue
-			}
-			var v ScannedDevice
-			err = note.JSONUnmarshal(line, &v)
-			if err != nil {
-				fmt.Printf("*** error converting record into inventory JSON: %s\n%s\n", err, line)
-			} else {
-				scannedDevices = append(scannedDevices, v)
-			}
-		}
-	}
-
-	// Read the SIM file into an array that we'll keep ordered
-	var scannedSIMs []ScannedSIM
-	contents, err = ioutil.ReadFile(simfile)
-	if err != nil {
-		fmt.Printf("*** new file: %s\n", simfile)
-	} else {
-		jrecs := bytes.Split(contents, []byte("\n"))
-		for i, line := range jrecs {
-			if i == 0 { // header row
-				continue
-			}
-			if len(line) == 0 {
-				continue
-			}
-			var v ScannedSIM
-			cols := strings.Split(string(line), ",")
-			if len(cols) == 3 {
-				v.Order = cols[0]
-				v.ICCID = cols[1]
-				v.Key = cols[2]
-				scannedSIMs = append(scannedSIMs, v)
-			}
-		}
-	}
+	// Read the existing inventory, which we'll keep ordered
+	scannedDevices, scannedSIMs := scanLoad(outfile, simfile)
 
 	// Loop, connecting with the card
+	cardsDone := 0
 	first := true
 	sawDisconnected := true
 	for {
@@ -144,13 +106,17 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 		// See if it's available
 		var rsp notecard.Request
 		card.DebugOutput(false, false)
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "hub.get"})
+		rsp, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
 		card.DebugOutput(debugEnabled, false)
 		if note.ErrorContains(err, note.ErrCardIo) {
 			if !sawDisconnected || first {
 				first = false
 				sawDisconnected = true
-				fmt.Printf("\n*** please insert the next notecard, or enter q to quit\n")
+				if cardsDone == 0 {
+					fmt.Printf("\n*** please insert the first notecard, or enter q to quit\n")
+				} else {
+					fmt.Printf("\n*** %d done; please insert the next notecard, or enter q to quit\n", cardsDone)
+				}
 			}
 			continue
 		}
@@ -170,14 +136,14 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 		if requestsString != "" {
 			req := notecard.Request{Req: "card.setup"}
 			req.Text = requestsString
-			rsp, err = card.TransactionRequest(req)
+			rsp, err = cardTransactionRequest(req)
 			if err != nil {
 				break
 			}
 			if !factoryReset {
-				card.TransactionRequest(notecard.Request{Req: "card.restart"})
+				cardTransactionRequest(notecard.Request{Req: "card.restart"})
 				for i := 0; i < 5; i++ {
-					_, err = card.TransactionRequest(notecard.Request{Req: "hub.get"})
+					_, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
 					if err == nil {
 						break
 					}
@@ -193,9 +159,9 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 		if factoryReset {
 			req := notecard.Request{Req: "card.restore"}
 			req.Delete = true
-			card.TransactionRequest(req)
+			cardTransactionRequest(req)
 			for i := 0; i < 5; i++ {
-				_, err = card.TransactionRequest(notecard.Request{Req: "hub.get"})
+				_, err = cardTransactionRequest(notecard.Request{Req: "hub.get"})
 				if err == nil {
 					break
 				}
@@ -216,13 +182,13 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 		// If requests were specified, process them
 		if len(requests) > 0 {
 			// Process the requests
-			err = processRequests(init, requests)
+			_, err = processRequests(init, requests, nil, nil)
 			if err != nil {
 				break
 			}
 			// Re-do the hub.get because the setup script may have changed things
 			card.DebugOutput(false, false)
-			rsp, _ = card.TransactionRequest(notecard.Request{Req: "hub.get"})
+			rsp, _ = cardTransactionRequest(notecard.Request{Req: "hub.get"})
 			card.DebugOutput(debugEnabled, false)
 		}
 
@@ -238,18 +204,18 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 
 		card.DebugOutput(false, false)
 
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.version"})
+		rsp, err = cardTransactionRequest(notecard.Request{Req: "card.version"})
 		if err == nil {
 			ir.Firmware = rsp.Version
 		}
 
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.usage.get"})
+		rsp, err = cardTransactionRequest(notecard.Request{Req: "card.usage.get"})
 		if err == nil {
 			ir.Provisioned = int64(rsp.Time)
 			ir.BytesUsed = rsp.BytesSent + rsp.BytesReceived
 		}
 
-		rsp, err = card.TransactionRequest(notecard.Request{Req: "card.test"})
+		rsp, err = cardTransactionRequest(notecard.Request{Req: "card.test"})
 		if err == nil {
 			note.BodyToObject(rsp.Body, &ir.Factory)
 		}
@@ -286,72 +252,136 @@ func scan(debugEnabled bool, init bool, fnSetup string, fnSetupSKU string, carri
 
 		}
 
-		// Delete this card from the array if it's there, and append it
-		found := false
-		for _, v := range scannedDevices {
-			if v.DeviceUID == ir.DeviceUID {
-				found = true
-				break
-			}
-		}
-		if found {
-			rnew := []ScannedDevice{}
-			for _, v := range scannedDevices {
-				if v.DeviceUID != ir.DeviceUID {
-					rnew = append(rnew, v)
-				}
-			}
-			scannedDevices = rnew
+		// Update the inventory and write it out
+		scannedDevices, scannedSIMs = scanMerge(scannedDevices, scannedSIMs, ir, sir)
+		err = scanSave(outfile, simfile, scannedDevices, scannedSIMs)
+		if err != nil {
+			return
 		}
-		scannedDevices = append(scannedDevices, ir)
 
-		// Delete this card from the SIM array if it's there, and append it
-		for i, v := range scannedSIMs {
-			if v.ICCID == sir.ICCID {
-				scannedSIMs = append(scannedSIMs[0:i], scannedSIMs[i+1:]...)
-				break
-			}
+		// Done
+		cardsDone++
+		fmt.Printf("\n*** please remove the notecard\n")
+
+		// When set up as one of several in parallel, we're done after the first
+		if scanOnce {
+			break
 		}
-		scannedSIMs = append(scannedSIMs, sir)
 
-		// Write the file
-		f, err2 := os.Create(outfile)
-		if err2 != nil {
-			err = err2
-			return
+		// Give the fixture time to settle before looking for the next card
+		if batchDelay > 0 {
+			time.Sleep(batchDelay)
 		}
-		w := bufio.NewWriter(f)
-		for _, v := range scannedDevices {
-			vj, err := note.JSONMarshal(v)
+
+	}
+
+	// Done
+	return
+}
+
+// Read a scan's device inventory and SIM files, either of which may not yet exist
+func scanLoad(outfile string, simfile string) (scannedDevices []ScannedDevice, scannedSIMs []ScannedSIM) {
+
+	contents, err := ioutil.ReadFile(outfile)
+	if err != nil {
+		fmt.Printf("*** new file: %s\n", outfile)
+	} else {
+		jrecs := bytes.Split(contents, []byte("\n"))
+		for _, line := range jrecs {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			var v ScannedDevice
+			err = note.JSONUnmarshal(line, &v)
 			if err != nil {
+				fmt.Printf("*** error converting record into inventory JSON: %s\n%s\n", err, line)
+			} else {
+				scannedDevices = append(scannedDevices, v)
+			}
+		}
+	}
+
+	contents, err = ioutil.ReadFile(simfile)
+	if err != nil {
+		fmt.Printf("*** new file: %s\n", simfile)
+	} else {
+		jrecs := bytes.Split(contents, []byte("\n"))
+		for i, line := range jrecs {
+			if i == 0 { // header row
 				continue
 			}
-			f.Write(vj)
-			f.Write([]byte("\r\n"))
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			var v ScannedSIM
+			cols := strings.Split(string(line), ",")
+			if len(cols) == 3 {
+				v.Order = cols[0]
+				v.ICCID = cols[1]
+				v.Key = cols[2]
+				scannedSIMs = append(scannedSIMs, v)
+			}
 		}
-		w.Flush()
-		f.Close()
+	}
 
-		// Write the SIM file
-		f, err2 = os.Create(simfile)
-		if err2 != nil {
-			err = err2
-			return
+	return
+}
+
+// Add a device and its SIM to the inventory, replacing any previous records for them
+func scanMerge(scannedDevices []ScannedDevice, scannedSIMs []ScannedSIM, ir ScannedDevice, sir ScannedSIM) ([]ScannedDevice, []ScannedSIM) {
+
+	rnew := []ScannedDevice{}
+	for _, v := range scannedDevices {
+		if v.DeviceUID != ir.DeviceUID {
+			rnew = append(rnew, v)
 		}
-		w = bufio.NewWriter(f)
-		f.WriteString("order_sid,iccid,registration_code\r\n")
-		for _, v := range scannedSIMs {
-			f.WriteString(fmt.Sprintf("%s,%s,%s\r\n", v.Order, v.ICCID, v.Key))
+	}
+	scannedDevices = append(rnew, ir)
+
+	for i, v := range scannedSIMs {
+		if v.ICCID == sir.ICCID {
+			scannedSIMs = append(scannedSIMs[0:i], scannedSIMs[i+1:]...)
+			break
 		}
-		w.Flush()
-		f.Close()
+	}
+	scannedSIMs = append(scannedSIMs, sir)
 
-		// Done
-		fmt.Printf("\n*** please remove the notecard\n")
+	return scannedDevices, scannedSIMs
+}
 
+// Write a scan's device inventory and SIM files
+func scanSave(outfile string, simfile string, scannedDevices []ScannedDevice, scannedSIMs []ScannedSIM) (err error) {
+
+	f, err := os.Create(outfile)
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(f)
+	for _, v := range scannedDevices {
+		vj, err := note.JSONMarshal(v)
+		if err != nil {
+			continue
+		}
+		w.Write(vj)
+		w.Write([]byte("\r\n"))
 	}
+	w.Flush()
+	f.Close()
+
+	f, err = os.Create(simfile)
+	if err != nil {
+		return
+	}
+	w = bufio.NewWriter(f)
+	w.WriteString("order_sid,iccid,registration_code\r\n")
+	for _, v := range scannedSIMs {
+		w.WriteString(fmt.Sprintf("%s,%s,%s\r\n", v.Order, v.ICCID, v.Key))
+	}
+	w.Flush()
+	f.Close()
 
-	// Done
 	return
 }
 
@@ -453,22 +483,50 @@ func loadRequestsString(filename string) (requests string, err error) {
 	return
 }
 
-// Process a set of requests
-func processRequests(init bool, requests []map[string]interface{}) (err error) {
+// RequestResult is the outcome of a single request issued by processRequests
+type RequestResult struct {
+	Index    int
+	Request  string
+	Response string
+	Err      error
+}
+
+// Print a summary of the results of processRequests, listing each request that failed
+func requestResultsSummary(results []RequestResult) {
+	failed := []RequestResult{}
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	fmt.Printf("%d of %d requests succeeded\n", len(results)-len(failed), len(results))
+	for _, r := range failed {
+		fmt.Printf("  #%d %s: %s\n", r.Index+1, r.Request, r.Err)
+	}
+}
+
+// Process a set of requests, optionally skipping those already applied by a prior attempt.
+// Progress is only recorded up to the first request that fails, so that it is retried.
+func processRequests(init bool, requests []map[string]interface{}, resume *setupResume, split *outputSplit) (results []RequestResult, err error) {
 	repeat := false
 	repeatForever := false
 	countLeft := int(0)
 	done := false
+	firstPass := true
+	failed := false
 	for !done {
-		if init {
+		if init && !(firstPass && resume.skip(0)) {
 			req := notecard.Request{Req: "card.restore"}
 			req.Delete = true
-			_, err = card.TransactionRequest(req)
+			_, err = cardTransactionRequest(req)
 			if err != nil {
 				break
 			}
 		}
-		for _, req := range requests {
+		for i, req := range requests {
+			if firstPass && resume.skip(i) && req["req"] != "repeat" {
+				continue
+			}
 			if req["req"] == "delay" {
 				n1, present := req["seconds"]
 				if present {
@@ -519,20 +577,50 @@ func processRequests(init bool, requests []map[string]interface{}) (err error) {
 					b["time"] = float64(time.Now().UTC().UnixNano()/1000000) / 1000
 				}
 			}
-			var reqJSON []byte
+			var reqJSON, rspJSON []byte
 			reqJSON, err = note.JSONMarshal(req)
 			if err != nil {
 				break
 			}
-			_, err = card.TransactionJSON(reqJSON)
+			result := RequestResult{Index: i, Request: string(reqJSON)}
+			rspJSON, err = cardTransactionJSON(reqJSON)
+			result.Response = strings.TrimSpace(string(rspJSON))
+			result.Err = err
+			if err == nil {
+				rsp := map[string]interface{}{}
+				note.JSONUnmarshal(rspJSON, &rsp)
+				rspErr, _ := rsp["err"].(string)
+				if rspErr != "" {
+					result.Err = fmt.Errorf("%s", rspErr)
+				}
+				_, hasPayload := rsp["payload"]
+				if hasPayload && split != nil {
+					var rspPayload notecard.Request
+					note.JSONUnmarshal(rspJSON, &rspPayload)
+					if rspPayload.Payload != nil {
+						_, err = split.write(*rspPayload.Payload)
+					}
+				}
+			}
+			results = append(results, result)
 			if err != nil {
 				break
 			}
+			if result.Err != nil {
+				failed = true
+			}
+			if firstPass && !failed {
+				resume.applied(i)
+			}
 		}
 		if !repeat {
 			break
 		}
+		firstPass = false
+	}
+	cardTransactionRequest(notecard.Request{Req: "card.checkpoint"})
+	if err == nil && !failed {
+		resume.completed()
 	}
-	card.TransactionRequest(notecard.Request{Req: "card.checkpoint"})
 	return
 }
@@ -0,0 +1,208 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blues/note-go/notecard"
+)
+
+// Flags that select the notecard or drive the batch scan, and that therefore must not be
+// passed through to the per-port scan of a parallel scan
+var scanParallelOmit = []string{"scan-parallel", "scan", "scan-once", "batch-delay", "interface", "port", "portconfig", "lease", "lease-mins"}
+
+// Result of setting up a single notecard during a parallel scan
+type ScanParallelResult struct {
+	Port      string
+	DeviceUID string
+	Elapsed   time.Duration
+	Err       error
+	LastMsg   string
+}
+
+// Build the arguments for scanning the single notecard on a port into its own inventory
+// file, passing through all of the setup flags (factory, setup, setup-sku, provision,
+// sideload, etc.) that we were given
+func scanParallelArgs(port string, outfile string) (args []string) {
+	args = []string{"-interface", notecard.NotecardInterfaceSerial, "-port", port, "-no-save", "-scan", outfile, "-scan-once"}
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		name := strings.TrimLeft(arg, "-")
+		hasValue := false
+		if equals := strings.Index(name, "="); equals >= 0 {
+			name = name[:equals]
+			hasValue = true
+		}
+		omit := false
+		for _, o := range scanParallelOmit {
+			if name == o {
+				omit = true
+			}
+		}
+		if !omit {
+			args = append(args, arg)
+			continue
+		}
+		// Skip the separate value of an omitted flag
+		if !hasValue && i+1 < len(os.Args) && !strings.HasPrefix(os.Args[i+1], "-") {
+			i++
+		}
+	}
+	return
+}
+
+// Scan all attached notecards, up to maxParallel at a time, each in its own process so
+// that each has its own notecard context, merging the results into a single inventory.
+// Successive notecards are started at least batchDelay apart.
+func scanParallel(outfile string, maxParallel int, batchDelay time.Duration) (err error) {
+
+	if maxParallel < 1 {
+		return fmt.Errorf("-scan-parallel must be at least 1")
+	}
+
+	// Require a json file, just as the scan itself does
+	if !strings.HasSuffix(outfile, ".json") {
+		if strings.Contains(outfile, ".") {
+			return fmt.Errorf("only the .json file type is supported")
+		}
+		outfile += ".json"
+	}
+	simfile := strings.TrimSuffix(outfile, ".json") + ".csv"
+
+	_, _, ports, err := notecard.SerialPorts()
+	if err != nil {
+		return
+	}
+	if len(ports) == 0 {
+		return fmt.Errorf("no notecards found on any serial port")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	// Each notecard's inventory goes into its own temporary file until all are done
+	tempDir, err := ioutil.TempDir("", "notecard-scan")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	fmt.Printf("setting up %d notecards, %d at a time\n", len(ports), maxParallel)
+
+	// Serialize console output so that lines from different notecards don't interleave
+	var consoleLock sync.Mutex
+	console := func(port string, line string) {
+		consoleLock.Lock()
+		fmt.Printf("%s: %s\n", port, line)
+		consoleLock.Unlock()
+	}
+
+	// Space out the start of each notecard's setup by the batch delay
+	var launchLock sync.Mutex
+	var lastLaunch time.Time
+	launchWait := func() {
+		launchLock.Lock()
+		if wait := batchDelay - time.Since(lastLaunch); !lastLaunch.IsZero() && wait > 0 {
+			time.Sleep(wait)
+		}
+		lastLaunch = time.Now()
+		launchLock.Unlock()
+	}
+
+	results := make([]ScanParallelResult, len(ports))
+	slots := make(chan bool, maxParallel)
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i int, port string) {
+			defer wg.Done()
+			slots <- true
+			defer func() { <-slots }()
+			launchWait()
+
+			result := ScanParallelResult{Port: port}
+			began := time.Now()
+
+			portfile := filepath.Join(tempDir, fmt.Sprintf("%d.json", i))
+			cmd := exec.Command(executable, scanParallelArgs(port, portfile)...)
+			stdout, err := cmd.StdoutPipe()
+			if err == nil {
+				cmd.Stderr = cmd.Stdout
+				err = cmd.Start()
+			}
+			if err == nil {
+				scanner := bufio.NewScanner(stdout)
+				for scanner.Scan() {
+					line := strings.TrimSpace(scanner.Text())
+					if line == "" {
+						continue
+					}
+					result.LastMsg = line
+					console(port, line)
+				}
+				err = cmd.Wait()
+			}
+
+			result.Elapsed = time.Since(began)
+			result.Err = err
+			results[i] = result
+		}(i, port)
+	}
+	wg.Wait()
+
+	// Merge each notecard's inventory into the combined inventory
+	scannedDevices, scannedSIMs := scanLoad(outfile, simfile)
+	for i := range results {
+		portfile := filepath.Join(tempDir, fmt.Sprintf("%d.json", i))
+		devices, sims := scanLoad(portfile, strings.TrimSuffix(portfile, ".json")+".csv")
+		for j, ir := range devices {
+			sir := ScannedSIM{}
+			if j < len(sims) {
+				sir = sims[j]
+			}
+			scannedDevices, scannedSIMs = scanMerge(scannedDevices, scannedSIMs, ir, sir)
+			results[i].DeviceUID = ir.DeviceUID
+		}
+	}
+	err = scanSave(outfile, simfile, scannedDevices, scannedSIMs)
+	if err != nil {
+		return
+	}
+
+	// Summarize
+	failed := 0
+	fmt.Printf("\n%-24s %-32s %-8s %8s  %s\n", "PORT", "DEVICE", "RESULT", "SECONDS", "DETAIL")
+	for _, result := range results {
+		status := "ok"
+		detail := ""
+		if result.Err != nil {
+			failed++
+			status = "FAILED"
+			detail = result.LastMsg
+			if detail == "" {
+				detail = result.Err.Error()
+			}
+		}
+		fmt.Printf("%-24s %-32s %-8s %8.0f  %s\n", result.Port, result.DeviceUID, status, result.Elapsed.Seconds(), detail)
+	}
+	fmt.Printf("%d of %d notecards set up successfully\n", len(results)-failed, len(results))
+	if failed > 0 {
+		err = fmt.Errorf("%d notecards failed setup", failed)
+	}
+
+	return
+
+}
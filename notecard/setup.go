@@ -0,0 +1,106 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notecard"
+)
+
+// SetupState records how far a -setup has progressed on a given device, so that it may be resumed
+type SetupState struct {
+	Setup   string `json:"setup,omitempty"`
+	MD5     string `json:"md5,omitempty"`
+	Applied int    `json:"applied,omitempty"`
+}
+
+// A setup resumption context, passed to processRequests
+type setupResume struct {
+	path  string
+	state SetupState
+}
+
+// Get the pathname of the setup state file for a device
+func setupStatePath(deviceUID string) string {
+	return lib.ConfigDir() + "/setup-" + strings.ReplaceAll(deviceUID, ":", "-") + ".json"
+}
+
+// Begin tracking the progress of a setup on the currently-connected card, picking up where a
+// prior attempt left off unless restart is specified
+func setupResumeBegin(setupFile string, requests []map[string]interface{}, restart bool) (resume *setupResume, err error) {
+
+	// Identify the card
+	var rsp notecard.Request
+	rsp, err = cardTransactionRequest(notecard.Request{Req: "card.version"})
+	if err != nil {
+		return
+	}
+	if rsp.DeviceUID == "" {
+		err = fmt.Errorf("can't resume setup: notecard did not report its DeviceUID")
+		return
+	}
+
+	// Fingerprint the requests so that we don't resume a setup whose contents changed
+	requestsJSON, _ := note.JSONMarshal(requests)
+	resume = &setupResume{}
+	resume.path = setupStatePath(rsp.DeviceUID)
+	resume.state.Setup = setupFile
+	resume.state.MD5 = fmt.Sprintf("%x", md5.Sum(requestsJSON))
+
+	// Start over if asked to
+	if restart {
+		os.Remove(resume.path)
+		return
+	}
+
+	// Load prior state
+	contents, err2 := ioutil.ReadFile(resume.path)
+	if err2 != nil {
+		return
+	}
+	var state SetupState
+	err2 = note.JSONUnmarshal(contents, &state)
+	if err2 != nil || state.MD5 != resume.state.MD5 {
+		fmt.Printf("setup state for %s is for a different setup file; starting over\n", rsp.DeviceUID)
+		return
+	}
+	resume.state.Applied = state.Applied
+	if resume.state.Applied > 0 {
+		fmt.Printf("resuming setup of %s after %d already-applied requests\n", rsp.DeviceUID, resume.state.Applied)
+	}
+
+	return
+
+}
+
+// Record that a request was successfully applied
+func (resume *setupResume) applied(index int) {
+	if resume == nil {
+		return
+	}
+	resume.state.Applied = index + 1
+	stateJSON, _ := note.JSONMarshalIndent(resume.state, "", "    ")
+	ioutil.WriteFile(resume.path, stateJSON, 0644)
+}
+
+// Returns true if the request at this index was already applied in a prior attempt
+func (resume *setupResume) skip(index int) bool {
+	return resume != nil && index < resume.state.Applied
+}
+
+// Discard the setup state because the setup completed
+func (resume *setupResume) completed() {
+	if resume == nil {
+		return
+	}
+	os.Remove(resume.path)
+}
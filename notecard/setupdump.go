@@ -0,0 +1,126 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/blues/note-go/note"
+)
+
+// Fields of hub.get that are replayed with hub.set, and of card.location.mode that are replayed as-is
+var setupDumpHubFields = []string{"product", "mode", "host", "outbound", "inbound", "voutbound", "vinbound", "sync", "align"}
+var setupDumpLocationFields = []string{"mode", "seconds", "vseconds", "threshold", "minutes", "lat", "lon", "max"}
+
+// Copy the fields that are present in a response into a request
+func setupDumpCopy(req map[string]interface{}, rsp map[string]interface{}, fields []string) {
+	for _, field := range fields {
+		if v, present := rsp[field]; present {
+			req[field] = v
+		}
+	}
+}
+
+// Capture the notecard's configurable state as a -setup file, one request per line, so that
+// it may be replayed onto other notecards.  Identity fields are included only if requested.
+func setupDump(filename string, includeIdentity bool) (err error) {
+
+	if !strings.HasSuffix(filename, ".json") {
+		if strings.Contains(filename, ".") {
+			return fmt.Errorf("requests must be in a .json file")
+		}
+		filename += ".json"
+	}
+
+	var out bytes.Buffer
+	emit := func(req map[string]interface{}) {
+		reqJSON, err := note.JSONMarshal(req)
+		if err == nil {
+			out.Write(reqJSON)
+			out.WriteString("\n")
+		}
+	}
+
+	// Identify the source of the configuration in a comment
+	version, err := cardTransactionMap(map[string]interface{}{"req": "card.version"})
+	if err != nil {
+		return
+	}
+	hub, err := cardTransactionMap(map[string]interface{}{"req": "hub.get"})
+	if err != nil {
+		return
+	}
+	source := "a notecard"
+	if includeIdentity {
+		source, _ = hub["device"].(string)
+	}
+	firmware, _ := version["version"].(string)
+	fmt.Fprintf(&out, "// captured from %s running %s\n", source, firmware)
+
+	// Hub configuration
+	hubSet := map[string]interface{}{"req": "hub.set"}
+	setupDumpCopy(hubSet, hub, setupDumpHubFields)
+	if includeIdentity {
+		setupDumpCopy(hubSet, hub, []string{"sn"})
+	}
+	emit(hubSet)
+
+	// Voltage profile, if not the default
+	voltage, err := cardTransactionMap(map[string]interface{}{"req": "card.voltage"})
+	if err == nil {
+		if mode, _ := voltage["mode"].(string); mode != "" && mode != "default" {
+			emit(map[string]interface{}{"req": "card.voltage", "mode": mode})
+		}
+	}
+
+	// Location mode
+	location, err := cardTransactionMap(map[string]interface{}{"req": "card.location.mode"})
+	if err != nil {
+		return
+	}
+	locationMode := map[string]interface{}{"req": "card.location.mode"}
+	setupDumpCopy(locationMode, location, setupDumpLocationFields)
+	emit(locationMode)
+
+	// ATTN configuration, to the extent that the notecard reports it
+	attn, err := cardTransactionMap(map[string]interface{}{"req": "card.attn"})
+	if err == nil {
+		if files, present := attn["files"]; present {
+			emit(map[string]interface{}{"req": "card.attn", "mode": "arm,files", "files": files})
+		} else {
+			out.WriteString("// card.attn did not report its configuration, so it is not captured\n")
+		}
+	}
+
+	// Environment variables become defaults, so that the cloud's values still take precedence
+	env, err := cardTransactionMap(map[string]interface{}{"req": "env.get"})
+	if err != nil {
+		return
+	}
+	body, _ := env["body"].(map[string]interface{})
+	names := []string{}
+	for name := range body {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		emit(map[string]interface{}{"req": "env.default", "name": name, "text": fmt.Sprint(body[name])})
+	}
+
+	// Templates can't be read back from the notecard
+	out.WriteString("// note templates cannot be read from the notecard and must be added by hand\n")
+
+	err = ioutil.WriteFile(filename, out.Bytes(), 0644)
+	if err != nil {
+		return
+	}
+	fmt.Printf("notecard configuration written to %s\n", filename)
+
+	return
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// How long to wait for the notecard to drop its session when cancelling a sync
+const syncCancelWaitSecs = 30
+
+// Cancel an in-progress sync.  The notecard has no request that aborts a sync, so its
+// connection is stopped by briefly turning the hub off, and its prior mode then restored.
+func syncCancel() (err error) {
+
+	hub, err := cardTransactionMap(map[string]interface{}{"req": "hub.get"})
+	if err != nil {
+		return
+	}
+	mode, _ := hub["mode"].(string)
+	if mode == "" || mode == "off" {
+		return fmt.Errorf("notecard is not configured to sync (mode is '%s')", mode)
+	}
+
+	fmt.Printf("cancelling sync by taking the notecard offline\n")
+	_, err = cardTransactionMap(map[string]interface{}{"req": "hub.set", "mode": "off"})
+	if err != nil {
+		return
+	}
+
+	// Wait for the session to end, then go back to the way we were
+	for i := 0; i < syncCancelWaitSecs; i++ {
+		status, err2 := cardTransactionMap(map[string]interface{}{"req": "hub.status"})
+		if err2 == nil {
+			if connected, _ := status["connected"].(bool); !connected {
+				break
+			}
+		}
+		time.Sleep(1 * time.Second)
+	}
+	fmt.Printf("restoring notecard to '%s' mode\n", mode)
+	_, err = cardTransactionMap(map[string]interface{}{"req": "hub.set", "mode": mode})
+	if err != nil {
+		return
+	}
+
+	// Report where the sync was left
+	status, err := cardTransactionMap(map[string]interface{}{"req": "hub.sync.status"})
+	if err != nil {
+		return
+	}
+	statusText, _ := status["status"].(string)
+	fmt.Printf("%24s: %s\n", "Sync Status", statusText)
+	if completed := int64(mapNumber(status, "completed")); completed > 0 {
+		fmt.Printf("%24s: %d seconds ago\n", "Last Completed Sync", completed)
+	}
+
+	return
+}
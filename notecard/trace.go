@@ -0,0 +1,83 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blues/note-go/note"
+	"github.com/fatih/color"
+)
+
+// Error keywords that cause a trace line to be highlighted
+var traceErrorKeywords = []string{
+	note.ErrCardIo,
+	note.ErrDFUNotReady,
+	note.ErrDFUInProgress,
+	note.ErrTransportDisconnected,
+	"{error}",
+	"{timeout}",
+	"{network}",
+}
+
+// Decorate a line of trace output with the time it was received and, if it contains an
+// error keyword, highlight it
+func traceDecorate(line string, timestamps bool, colorize bool) string {
+	if colorize {
+		highlight := strings.Contains(strings.ToLower(line), "error")
+		for _, keyword := range traceErrorKeywords {
+			if note.ErrorContains(fmt.Errorf("%s", line), keyword) {
+				highlight = true
+			}
+		}
+		if highlight {
+			line = color.RedString("%s", line)
+		}
+	}
+	if timestamps {
+		line = time.Now().Format("15:04:05.000") + " " + line
+	}
+	return line
+}
+
+// Watch the notecard's trace output, optionally timestamping and colorizing each line.
+// The trace is rendered by the notecard library directly to stdout, so when decorating
+// it we capture stdout and rewrite it a line at a time.
+func trace(timestamps bool, colorize bool) (err error) {
+
+	if !timestamps && !colorize {
+		return card.Trace()
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan bool)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			fmt.Fprintln(stdout, traceDecorate(strings.TrimRight(scanner.Text(), "\r"), timestamps, colorize))
+		}
+		done <- true
+	}()
+
+	err = card.Trace()
+
+	os.Stdout = stdout
+	w.Close()
+	<-done
+	r.Close()
+
+	return
+
+}
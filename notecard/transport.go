@@ -0,0 +1,196 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notecard"
+)
+
+// Requests after which the notecard restarts, which on USB may cause the port to re-enumerate
+var cardResetRequests = map[string]bool{
+	"card.restart": true,
+	"card.restore": true,
+}
+
+// Set by -serial-reconnect-on-reset to re-open the port when it disappears across a restart
+var cardReconnectOnReset bool
+
+// How the card was opened, so that it may be re-opened
+var cardOpenInterface string
+var cardOpenPort string
+var cardOpenConfig int
+
+// True if a request that restarts the notecard has been issued and the port has not yet been proven
+var cardResetPending bool
+
+// How long to keep trying to re-open the port after a restart
+const cardReopenTimeoutSecs = 30
+
+// Set by -timeout to bound how long any single transaction may take
+var cardTimeout time.Duration
+
+// Open the notecard, remembering how it was opened
+func cardOpen(iface string, port string, portConfig int) (err error) {
+	cardOpenInterface = iface
+	cardOpenPort = port
+	cardOpenConfig = portConfig
+	card, err = notecard.Open(iface, port, portConfig)
+	return
+}
+
+// Re-open the notecard's port, backing off between attempts while the port re-enumerates
+func cardReopen() (err error) {
+	delay := 500 * time.Millisecond
+	began := time.Now()
+	for {
+		if card != nil {
+			card.Close()
+		}
+		card, err = notecard.Open(cardOpenInterface, cardOpenPort, cardOpenConfig)
+		if err == nil {
+			return
+		}
+		if time.Since(began).Seconds() > cardReopenTimeoutSecs {
+			return fmt.Errorf("can't re-open %s after the notecard restarted: %s", cardOpenPort, err)
+		}
+		time.Sleep(delay)
+		if delay < 4*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// Run a transaction, failing with {timeout} if it doesn't complete within -timeout.  The
+// abandoned transaction can't be cancelled, so the port is closed to make it fail and is then
+// re-opened, ensuring that no later transaction shares the port with it.
+func cardWithTimeout(transaction func() error) (timedOut bool, err error) {
+	if cardTimeout == 0 {
+		return false, transaction()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- transaction()
+	}()
+	select {
+	case err = <-done:
+		return
+	case <-time.After(cardTimeout):
+	}
+
+	timedOut = true
+	card.Close()
+	select {
+	case <-done:
+	case <-time.After(cardReopenTimeoutSecs * time.Second):
+		return timedOut, fmt.Errorf("transaction did not complete within %s and %s could not be released {timeout}", cardTimeout, cardOpenPort)
+	}
+	err = cardReopen()
+	if err != nil {
+		return
+	}
+	err = fmt.Errorf("transaction did not complete within %s {timeout}", cardTimeout)
+	return
+}
+
+// Run a transaction, bounded by -timeout.  If -serial-reconnect-on-reset is specified and a
+// transaction fails after a request that restarted the notecard, the port is re-opened and
+// the transaction is retried.
+func cardTransaction(reqName string, transaction func() error) (timedOut bool, err error) {
+
+	timedOut, err = cardWithTimeout(transaction)
+	if err != nil && !timedOut && cardReconnectOnReset && cardResetPending && note.ErrorContains(err, note.ErrCardIo) {
+		err = cardReopen()
+		if err != nil {
+			return
+		}
+		timedOut, err = cardWithTimeout(transaction)
+	}
+	if err == nil {
+		cardResetPending = false
+	}
+	if cardResetRequests[reqName] {
+		cardResetPending = true
+	}
+
+	return
+
+}
+
+// Perform a request
+func cardTransactionRequest(req notecard.Request) (rsp notecard.Request, err error) {
+	var result notecard.Request
+	timedOut, err := cardTransaction(req.Req, func() (err error) {
+		result, err = card.TransactionRequest(req)
+		return
+	})
+	if !timedOut {
+		rsp = result
+	}
+	return
+}
+
+// Perform a JSON transaction
+func cardTransactionJSON(reqJSON []byte) (rspJSON []byte, err error) {
+	var req notecard.Request
+	note.JSONUnmarshal(reqJSON, &req)
+	var result []byte
+	timedOut, err := cardTransaction(req.Req, func() (err error) {
+		result, err = card.TransactionJSON(reqJSON)
+		return
+	})
+	if !timedOut {
+		rspJSON = result
+	}
+	return
+}
+
+// Perform a transaction using a generic JSON object, promoting an "err" field to an error
+func cardTransactionMap(req map[string]interface{}) (rsp map[string]interface{}, err error) {
+	var reqJSON, rspJSON []byte
+	reqJSON, err = note.JSONMarshal(req)
+	if err != nil {
+		return
+	}
+	rspJSON, err = cardTransactionJSON(reqJSON)
+	if err != nil {
+		return
+	}
+	rsp = map[string]interface{}{}
+	err = note.JSONUnmarshal(rspJSON, &rsp)
+	if err != nil {
+		return
+	}
+	rspErr, _ := rsp["err"].(string)
+	if rspErr != "" {
+		err = fmt.Errorf("%s", rspErr)
+	}
+	return
+}
+
+// Extract a numeric field from a generic JSON object, returning 0 if absent
+func mapNumber(m map[string]interface{}, key string) float64 {
+	switch v := m[key].(type) {
+	case json.Number:
+		n, _ := v.Float64()
+		return n
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// Format a unix epoch time in both UTC and local time
+func formatEpochTime(secs float64) string {
+	if secs <= 0 {
+		return "-"
+	}
+	return time.Unix(int64(secs), 0).UTC().Format("2006-01-02T15:04:05Z") + " (" +
+		time.Unix(int64(secs), 0).Local().Format("2006-01-02 3:04:05 PM MST") + ")"
+}
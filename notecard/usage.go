@@ -0,0 +1,63 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fields of card.usage.get, in display order
+var usageFields = []string{
+	"bytes_sent", "Bytes Sent",
+	"bytes_received", "Bytes Received",
+	"notes_sent", "Notes Sent",
+	"notes_received", "Notes Received",
+	"sessions_standard", "Standard Sessions",
+	"sessions_secure", "Secure Sessions",
+}
+
+// Display a detailed breakdown of the notecard's data usage
+func usageShow() (err error) {
+
+	// Usage since the counters were last reset
+	sinceReset, err := cardTransactionMap(map[string]interface{}{"req": "card.usage.get"})
+	if err != nil {
+		return
+	}
+
+	// Usage since the notecard was provisioned
+	sinceProvisioned, err := cardTransactionMap(map[string]interface{}{"req": "card.usage.get", "mode": "total"})
+	if err != nil {
+		return
+	}
+
+	// Display them side-by-side
+	fmt.Printf("                          %18s %18s\n", "Since Provisioned", "Since Reset")
+	fmt.Printf("             Provisioned: %s\n", formatEpochTime(mapNumber(sinceProvisioned, "time")))
+	for i := 0; i < len(usageFields)/2; i++ {
+		key := usageFields[i*2]
+		label := usageFields[i*2+1]
+		fmt.Printf("%24s: %18d %18d\n", label, int64(mapNumber(sinceProvisioned, key)), int64(mapNumber(sinceReset, key)))
+	}
+	total := mapNumber(sinceProvisioned, "bytes_sent") + mapNumber(sinceProvisioned, "bytes_received")
+	totalReset := mapNumber(sinceReset, "bytes_sent") + mapNumber(sinceReset, "bytes_received")
+	fmt.Printf("%24s: %18d %18d\n", "Total Bytes", int64(total), int64(totalReset))
+
+	return
+
+}
+
+// Reset the notecard's data usage counters
+func usageReset() (err error) {
+	_, err = cardTransactionMap(map[string]interface{}{"req": "card.usage", "reset": true})
+	if err != nil && strings.Contains(err.Error(), "{not-supported}") {
+		err = fmt.Errorf("this notecard does not support resetting usage counters")
+	}
+	if err == nil {
+		fmt.Printf("usage counters reset\n")
+	}
+	return
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notecard"
+)
+
+// A sync log entry, as dequeued from _synclog.qi
+type watchSyncLog struct {
+	TimeSecs  int64  `json:"time,omitempty"`
+	Level     int    `json:"level,omitempty"`
+	Subsystem string `json:"subsystem,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Display the notecard's sync status log at or below the specified detail level until
+// interrupted or, if either is non-zero, for a bounded number of seconds or lines
+func watch(level int, seconds int, count int) (err error) {
+
+	if level < 0 || level > 5 {
+		return fmt.Errorf("-watch level must be between 0 and 5")
+	}
+
+	began := time.Now()
+	lines := 0
+	for {
+
+		if seconds > 0 && time.Since(began) >= time.Duration(seconds)*time.Second {
+			return
+		}
+		if count > 0 && lines >= count {
+			return
+		}
+
+		req := notecard.Request{Req: "note.get", NotefileID: "_synclog.qi", Delete: true}
+		rsp, err2 := cardTransactionRequest(req)
+		if err2 != nil {
+			if !note.ErrorContains(err2, note.ErrNoteNoExist) {
+				return err2
+			}
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		if rsp.Body == nil {
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		var bodyJSON []byte
+		bodyJSON, err = note.ObjectToJSON(rsp.Body)
+		if err != nil {
+			return
+		}
+		var entry watchSyncLog
+		err = note.JSONUnmarshal(bodyJSON, &entry)
+		if err != nil {
+			return
+		}
+		if entry.Level > level {
+			continue
+		}
+
+		dateString := "--/--/---- --:--:--"
+		if entry.TimeSecs > 0 {
+			dateString = time.Unix(entry.TimeSecs, 0).Format("01/02/2006 15:04:05")
+		}
+		fmt.Printf("%s [%-10s] %s\n", dateString, entry.Subsystem, entry.Text)
+		lines++
+
+	}
+
+}
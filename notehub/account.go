@@ -0,0 +1,91 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// AccountProject is a project accessible to the signed-in account, along with its fleets
+type AccountProject struct {
+	UID    string  `json:"uid,omitempty"`
+	Label  string  `json:"label,omitempty"`
+	Fleets []Fleet `json:"fleets"`
+}
+
+// AccountProjectsResponse is the list of projects accessible to the signed-in account
+type AccountProjectsResponse struct {
+	Projects []AccountProject `json:"projects,omitempty"`
+}
+
+// List the fleets of every project accessible to the signed-in account, grouped by project
+func accountFleets(flagJSON bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	rsp := AccountProjectsResponse{}
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", "/v1/projects", nil, &rsp)
+	if err != nil {
+		return
+	}
+	projects := rsp.Projects
+	sort.Slice(projects, func(i, j int) bool {
+		return strings.ToLower(projects[i].Label) < strings.ToLower(projects[j].Label)
+	})
+
+	// Gather the fleets of each project, noting those that we can't read
+	for i := range projects {
+		var fleets []map[string]interface{}
+		fleets, err = projectList(projects[i].UID, "fleets", flagVerbose)
+		if err != nil {
+			if !flagJSON {
+				fmt.Printf("*** %s (%s): %s\n", projects[i].Label, projects[i].UID, err)
+			}
+			err = nil
+			continue
+		}
+		projects[i].Fleets = []Fleet{}
+		for _, f := range fleets {
+			uid, _ := f["uid"].(string)
+			label, _ := f["label"].(string)
+			projects[i].Fleets = append(projects[i].Fleets, Fleet{UID: uid, Label: label})
+		}
+	}
+
+	if flagJSON {
+		var projectsJSON []byte
+		if flagPretty {
+			projectsJSON, err = note.JSONMarshalIndent(projects, "", "    ")
+		} else {
+			projectsJSON, err = note.JSONMarshal(projects)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", projectsJSON)
+		}
+		return
+	}
+
+	fleetCount := 0
+	for _, p := range projects {
+		if p.Fleets == nil {
+			continue
+		}
+		fmt.Printf("\n%s (%s)\n", p.Label, p.UID)
+		if len(p.Fleets) == 0 {
+			fmt.Printf("    (no fleets)\n")
+		}
+		for _, f := range p.Fleets {
+			fmt.Printf("    %-40s %s\n", f.Label, f.UID)
+		}
+		fleetCount += len(p.Fleets)
+	}
+	fmt.Printf("\n%d fleets in %d projects\n", fleetCount, len(projects))
+
+	return
+
+}
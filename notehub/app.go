@@ -7,12 +7,14 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/csv"
 	"fmt"
 	"io/ioutil"
 	"sort"
 	"strings"
 
 	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
 	notegoapi "github.com/blues/note-go/notehub/api"
 )
 
@@ -140,17 +142,15 @@ func appGetScope(scope string, flagVerbose bool) (appMetadata AppMetadata, scope
 		return
 	}
 
-	// On the command line (but not inside files) we allow comma-separated lists
-	if strings.Contains(scope, ",") {
-		scopeList := strings.Split(scope, ",")
-		for _, scope := range scopeList {
+	// On the command line (but not inside files) we allow comma-separated lists, in which
+	// elements prefixed with ! or - are excluded from the result of the others
+	var excludeDevices, excludeFleets []string
+	for _, scope := range strings.Split(scope, ",") {
+		if len(scope) > 1 && (strings.HasPrefix(scope, "!") || strings.HasPrefix(scope, "-")) {
+			err = addScope(scope[1:], &appMetadata, &excludeDevices, &excludeFleets, flagVerbose)
+		} else {
 			err = addScope(scope, &appMetadata, &scopeDevices, &scopeFleets, flagVerbose)
-			if err != nil {
-				return
-			}
 		}
-	} else {
-		err = addScope(scope, &appMetadata, &scopeDevices, &scopeFleets, flagVerbose)
 		if err != nil {
 			return
 		}
@@ -160,6 +160,10 @@ func appGetScope(scope string, flagVerbose bool) (appMetadata AppMetadata, scope
 	scopeDevices = sortAndRemoveDuplicates(scopeDevices)
 	scopeFleets = sortAndRemoveDuplicates(scopeFleets)
 
+	// Remove exclusions
+	scopeDevices = removeExcluded(scopeDevices, excludeDevices)
+	scopeFleets = removeExcluded(scopeFleets, excludeFleets)
+
 	// Done
 	return
 
@@ -207,32 +211,10 @@ func addScope(scope string, appMetadata *AppMetadata, scopeDevices *[]string, sc
 	// Looking for "all devices" or a named fleet
 	if indirectScope == "" {
 		// All devices
-
-		pageSize := 500
-		pageNum := 0
-		for {
-			pageNum++
-
-			devices := notegoapi.GetDevicesResponse{}
-			url := fmt.Sprintf("/v1/projects/%s/devices?pageSize=%d&pageNum=%d", appMetadata.App.UID, pageSize, pageNum)
-			err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &devices)
-			if err != nil {
-				return
-			}
-
-			for _, device := range devices.Devices {
-				err = addScope(device.UID, appMetadata, scopeDevices, scopeFleets, flagVerbose)
-				if err != nil {
-					return err
-				}
-			}
-
-			if !devices.HasMore {
-				break
-			}
-
-		}
-
+		url := fmt.Sprintf("/v1/projects/%s/devices", appMetadata.App.UID)
+		err = paginate(url, 500, flagVerbose, func(page []byte) error {
+			return addScopeDevicesPage(page, appMetadata, scopeDevices, scopeFleets, flagVerbose)
+		})
 		return
 
 	} else {
@@ -241,30 +223,12 @@ func addScope(scope string, appMetadata *AppMetadata, scopeDevices *[]string, sc
 		for _, fleet := range (*appMetadata).Fleets {
 			if lookingFor == fleet.UID || fleetMatchesScope(fleet.Name, lookingFor) {
 				foundFleet = true
-
-				pageSize := 100
-				pageNum := 0
-				for {
-					pageNum++
-
-					devices := notegoapi.GetDevicesResponse{}
-					url := fmt.Sprintf("/v1/projects/%s/fleets/%s/devices?pageSize=%d&pageNum=%d", appMetadata.App.UID, fleet.UID, pageSize, pageNum)
-					err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &devices)
-					if err != nil {
-						return
-					}
-
-					for _, device := range devices.Devices {
-						err = addScope(device.UID, appMetadata, scopeDevices, scopeFleets, flagVerbose)
-						if err != nil {
-							return err
-						}
-					}
-
-					if !devices.HasMore {
-						break
-					}
-
+				url := fmt.Sprintf("/v1/projects/%s/fleets/%s/devices", appMetadata.App.UID, fleet.UID)
+				err = paginate(url, 100, flagVerbose, func(page []byte) error {
+					return addScopeDevicesPage(page, appMetadata, scopeDevices, scopeFleets, flagVerbose)
+				})
+				if err != nil {
+					return
 				}
 
 			}
@@ -282,6 +246,11 @@ func addScope(scope string, appMetadata *AppMetadata, scopeDevices *[]string, sc
 		return fmt.Errorf("%s: %s", indirectScope, err)
 	}
 
+	// A CSV file, such as an inventory export, is indirected through a column named in its header
+	if strings.HasSuffix(strings.ToLower(indirectScope), ".csv") {
+		return addScopeCSV(indirectScope, contents, appMetadata, scopeDevices, scopeFleets, flagVerbose)
+	}
+
 	scanner := bufio.NewScanner(bytes.NewReader(contents))
 	scanner.Split(bufio.ScanLines)
 
@@ -300,6 +269,62 @@ func addScope(scope string, appMetadata *AppMetadata, scopeDevices *[]string, sc
 
 }
 
+// Add the values of the -scope-column column of a CSV file, skipping its header and blank cells
+func addScopeCSV(filename string, contents []byte, appMetadata *AppMetadata, scopeDevices *[]string, scopeFleets *[]string, flagVerbose bool) (err error) {
+
+	reader := csv.NewReader(bytes.NewReader(contents))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s: file is empty", filename)
+	}
+
+	column := -1
+	for i, name := range records[0] {
+		if strings.EqualFold(strings.TrimSpace(name), flagScopeColumn) {
+			column = i
+			break
+		}
+	}
+	if column < 0 {
+		return fmt.Errorf("%s: no column named '%s' (use -scope-column to specify it)", filename, flagScopeColumn)
+	}
+
+	for _, record := range records[1:] {
+		if column >= len(record) {
+			continue
+		}
+		if value := strings.TrimSpace(record[column]); value != "" {
+			err = addScope(value, appMetadata, scopeDevices, scopeFleets, flagVerbose)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+
+}
+
+// Add all the devices in a page of a device list to the scope
+func addScopeDevicesPage(page []byte, appMetadata *AppMetadata, scopeDevices *[]string, scopeFleets *[]string, flagVerbose bool) (err error) {
+	devices := notegoapi.GetDevicesResponse{}
+	err = note.JSONUnmarshal(page, &devices)
+	if err != nil {
+		return
+	}
+	for _, device := range devices.Devices {
+		err = addScope(device.UID, appMetadata, scopeDevices, scopeFleets, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // Sort and remove duplicates in a string slice
 func sortAndRemoveDuplicates(strings []string) []string {
 
@@ -318,6 +343,28 @@ func sortAndRemoveDuplicates(strings []string) []string {
 	return result
 }
 
+// Remove the excluded strings from a string slice, preserving its order
+func removeExcluded(uids []string, excluded []string) []string {
+
+	if len(excluded) == 0 {
+		return uids
+	}
+
+	exclude := make(map[string]struct{})
+	for _, v := range excluded {
+		exclude[v] = struct{}{}
+	}
+
+	var result []string
+	for _, v := range uids {
+		if _, skip := exclude[v]; !skip {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
 // See if a fleet name matches a scope name
 func fleetMatchesScope(fleetName string, scope string) bool {
 	normalizedScope := strings.ToLower(scope)
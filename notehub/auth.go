@@ -13,9 +13,9 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/blues/note-cli/lib"
-	"github.com/blues/note-go/notehub"
 	terminal "golang.org/x/term"
 )
 
@@ -40,11 +40,7 @@ func authSignInToken(token string) (err error) {
 	if lib.Config.HubCreds == nil {
 		lib.Config.HubCreds = map[string]lib.ConfigCreds{}
 	}
-	hub := lib.Config.Hub
-	if hub == "" {
-		hub = notehub.DefaultAPIService
-	}
-	lib.Config.HubCreds[hub] = creds
+	lib.Config.HubCreds[lib.ConfigCredsKey()] = creds
 	err = lib.ConfigWrite()
 	if err != nil {
 		return
@@ -112,7 +108,7 @@ func authSignIn() (err error) {
 		err = err2
 		return
 	}
-	httpReq.Header.Set("User-Agent", "notehub-client")
+	httpSetHeaders(httpReq)
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpClient := &http.Client{}
 	httpRsp, err2 := httpClient.Do(httpReq)
@@ -152,14 +148,13 @@ func authSignIn() (err error) {
 	var creds lib.ConfigCreds
 	creds.Token = token
 	creds.User = username
+	if expires, ok := rsp["expires_at"].(float64); ok {
+		creds.Expires = int64(expires)
+	}
 	if lib.Config.HubCreds == nil {
 		lib.Config.HubCreds = map[string]lib.ConfigCreds{}
 	}
-	hub := lib.Config.Hub
-	if hub == "" {
-		hub = notehub.DefaultAPIService
-	}
-	lib.Config.HubCreds[hub] = creds
+	lib.Config.HubCreds[lib.ConfigCredsKey()] = creds
 	err = lib.ConfigWrite()
 	if err != nil {
 		return
@@ -183,12 +178,10 @@ func authSignOut() (err error) {
 
 	// Get the token, and clear it
 	if lib.Config.HubCreds != nil {
-		hub := lib.Config.Hub
-		if hub == "" {
+		if lib.Config.Hub == "" && lib.ConfigProfile() == "" {
 			delete(lib.Config.HubCreds, "")
-			hub = notehub.DefaultAPIService
 		}
-		delete(lib.Config.HubCreds, hub)
+		delete(lib.Config.HubCreds, lib.ConfigCredsKey())
 	}
 	err = lib.ConfigWrite()
 	if err != nil {
@@ -202,7 +195,7 @@ func authSignOut() (err error) {
 		err = err2
 		return
 	}
-	httpReq.Header.Set("User-Agent", "notehub-client")
+	httpSetHeaders(httpReq)
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("X-Session-Token", token)
 	httpClient := &http.Client{}
@@ -241,6 +234,45 @@ func authToken() (user string, token string, err error) {
 	return
 }
 
+// Show the identity that is signed in to the configured notehub, and verify that the hub
+// still accepts its token
+func authWhoAmI(flagVerbose bool) (err error) {
+
+	user, token, err := authToken()
+	if err != nil {
+		return
+	}
+
+	tokenType := "session token (from -signin)"
+	if user == "(token)" {
+		tokenType = "personal access token (from -signin-token)"
+	}
+	fmt.Printf("%24s: %s\n", "user", user)
+	fmt.Printf("%24s: %s\n", "hub", lib.ConfigAPIHub())
+	if profile := lib.ConfigProfile(); profile != "" {
+		fmt.Printf("%24s: %s\n", "profile", profile)
+	}
+	fmt.Printf("%24s: %s\n", "token type", tokenType)
+	if _, remaining := lib.ConfigTokenExpiring(); remaining != 0 {
+		fmt.Printf("%24s: %s\n", "expires in", remaining.Round(time.Second))
+	}
+	if len(token) > 8 {
+		fmt.Printf("%24s: %s...%s\n", "token", token[:4], token[len(token)-4:])
+	}
+
+	// Any authenticated request will do to verify that the token is still valid
+	rsp := AccountProjectsResponse{}
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", "/v1/projects", nil, &rsp)
+	if err != nil {
+		fmt.Printf("%24s: rejected by the hub\n", "status")
+		return fmt.Errorf("token is no longer valid: %s", err)
+	}
+	fmt.Printf("%24s: valid, with access to %d projects\n", "status", len(rsp.Projects))
+
+	return
+
+}
+
 // Banner for authentication
 // http://patorjk.com/software/taag
 // "Big" font
@@ -0,0 +1,612 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notehub"
+)
+
+// The outbound notefile into which audit events are added on behalf of a device, so that
+// they appear in the project's event stream alongside the device's own events
+const deviceAuditNotefile = "_audit.qo"
+
+// The notefile into which requests are queued for the notecard to perform when it next syncs
+const deviceRequestNotefile = "_req.qis"
+
+// DeviceAudit is the body of an audit event recorded for a device
+type DeviceAudit struct {
+	Action string `json:"action,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	User   string `json:"user,omitempty"`
+	Time   string `json:"time,omitempty"`
+}
+
+// Record an audit event for the device describing an action taken upon it
+func deviceAudit(deviceUID string, action string, reason string, flagVerbose bool) (err error) {
+
+	audit := DeviceAudit{}
+	audit.Action = action
+	audit.Reason = reason
+	audit.User, _, _ = lib.ConfigSignedIn()
+	audit.Time = time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	var body map[string]interface{}
+	body, err = note.ObjectToBody(audit)
+	if err != nil {
+		return
+	}
+
+	// Hub requests are addressed to the device in flagDevice
+	saveDevice := flagDevice
+	flagDevice = deviceUID
+	defer func() { flagDevice = saveDevice }()
+
+	req := notehub.HubRequest{}
+	req.Req = "note.add"
+	req.NotefileID = deviceAuditNotefile
+	req.Body = &body
+	_, err = hubTransactionRequest(req, flagVerbose)
+	return
+
+}
+
+// Enable or disable devices, recording the reason for doing so
+func deviceSetEnabled(appMetadata AppMetadata, uids []string, enable bool, reason string, dryRun bool, flagVerbose bool) (err error) {
+
+	action := "disable"
+	if enable {
+		action = "enable"
+	}
+
+	for _, deviceUID := range uids {
+
+		if dryRun {
+			fmt.Printf("(dry run) %s would be %sd\n", deviceUID, action)
+			continue
+		}
+
+		url := fmt.Sprintf("/v1/projects/%s/devices/%s/%s", appMetadata.App.UID, deviceUID, action)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "POST", url, nil, nil)
+		if err != nil {
+			return
+		}
+
+		if reason != "" {
+			err = deviceAudit(deviceUID, action, reason, flagVerbose)
+			if err != nil {
+				return fmt.Errorf("%s was %sd but the reason could not be recorded: %s", deviceUID, action, err)
+			}
+		}
+
+		fmt.Printf("%s %sd\n", deviceUID, action)
+
+	}
+
+	return
+
+}
+
+// Queue a factory reset of the notecards of devices, to be performed when each next syncs
+func deviceFactoryReset(appMetadata AppMetadata, uids []string, confirmed bool, reason string, dryRun bool, flagVerbose bool) (err error) {
+
+	if dryRun {
+		for _, deviceUID := range uids {
+			fmt.Printf("(dry run) %s would have a factory reset queued for next sync\n", deviceUID)
+		}
+		return
+	}
+
+	if !confirmed {
+		return fmt.Errorf("this will erase all configuration and data on the notecards of %d devices when they next sync; use -yes to confirm", len(uids))
+	}
+
+	// Hub requests are addressed to the device in flagDevice
+	saveDevice := flagDevice
+	defer func() { flagDevice = saveDevice }()
+
+	queued := 0
+	for _, deviceUID := range uids {
+
+		flagDevice = deviceUID
+		body := map[string]interface{}{"req": "card.restore", "delete": true}
+		req := notehub.HubRequest{}
+		req.Req = "note.add"
+		req.NotefileID = deviceRequestNotefile
+		req.Body = &body
+		_, err = hubTransactionRequest(req, flagVerbose)
+		if err != nil {
+			fmt.Printf("%s NOT queued: %s\n", deviceUID, err)
+			continue
+		}
+		queued++
+		fmt.Printf("%s factory reset queued for next sync\n", deviceUID)
+
+		if reason != "" {
+			err = deviceAudit(deviceUID, "factory-reset", reason, flagVerbose)
+			if err != nil {
+				fmt.Printf("%s reason could not be recorded: %s\n", deviceUID, err)
+			}
+		}
+
+	}
+
+	err = nil
+	if queued != len(uids) {
+		err = fmt.Errorf("factory reset queued for %d of %d devices", queued, len(uids))
+	}
+
+	return
+
+}
+
+// Enable each device listed in a CSV of device UIDs with an optional target fleet, moving
+// each into its fleet, and write a CSV recording the outcome for every device
+func deviceBulkEnable(appMetadata AppMetadata, infile string, outfile string, reason string, flagVerbose bool) (err error) {
+
+	f, err := os.Open(infile)
+	if err != nil {
+		return
+	}
+	rows, err := csv.NewReader(f).ReadAll()
+	f.Close()
+	if err != nil {
+		return
+	}
+
+	// Progress goes to the console, so the results must go to a file
+	if outfile == "" {
+		return fmt.Errorf("use -out to specify the file into which results are written")
+	}
+	of, err := os.Create(outfile)
+	if err != nil {
+		return
+	}
+	defer of.Close()
+	results := csv.NewWriter(of)
+	defer results.Flush()
+	results.Write([]string{"device", "fleet", "result", "error", "time"})
+
+	enabled := 0
+	failed := 0
+	for i, row := range rows {
+
+		deviceUID := ""
+		if len(row) > 0 {
+			deviceUID = strings.TrimSpace(row[0])
+		}
+		fleet := ""
+		if len(row) > 1 {
+			fleet = strings.TrimSpace(row[1])
+		}
+
+		// Skip blank lines and a header row
+		if deviceUID == "" || (i == 0 && !strings.HasPrefix(deviceUID, "dev:")) {
+			continue
+		}
+
+		err = deviceSetEnabled(appMetadata, []string{deviceUID}, true, reason, false, flagVerbose)
+		if err == nil && fleet != "" {
+			err = fleetMoveDevices(appMetadata, []string{deviceUID}, fleet, false, false, flagVerbose)
+		}
+		result := "enabled"
+		errstr := ""
+		if err != nil {
+			failed++
+			result = "failed"
+			errstr = err.Error()
+			fmt.Printf("%s NOT enabled: %s\n", deviceUID, err)
+		} else {
+			enabled++
+		}
+		results.Write([]string{deviceUID, fleet, result, errstr, time.Now().UTC().Format("2006-01-02T15:04:05Z")})
+		results.Flush()
+
+	}
+
+	err = nil
+	fmt.Printf("%d devices enabled, %d failed; results written to %s\n", enabled, failed, outfile)
+	if failed > 0 {
+		err = fmt.Errorf("%d of %d devices could not be enabled", failed, enabled+failed)
+	}
+
+	return
+
+}
+
+// Default number of devices per page when listing devices
+const deviceListPageSize = 50
+
+// DeviceListFilter selects the devices shown by a device list.  Fleet is a fleet name or UID;
+// the others match case-insensitively anywhere within the corresponding device field.
+type DeviceListFilter struct {
+	Fleet            string
+	SKU              string
+	NotecardFirmware string
+	HostFirmware     string
+	Tags             string
+	TagMatch         string
+}
+
+// Determine whether a device satisfies the client-side portion of a filter
+func (f DeviceListFilter) matches(device DeviceSummary) bool {
+	contains := func(field string, want string) bool {
+		return want == "" || strings.Contains(strings.ToLower(field), strings.ToLower(want))
+	}
+	return contains(device.SKU, f.SKU) &&
+		contains(device.FirmwareNotecard, f.NotecardFirmware) &&
+		contains(device.FirmwareHost, f.HostFirmware) &&
+		f.matchesTags(device)
+}
+
+// Determine whether a device carries all (or, with a tag match of any, at least one) of the
+// comma-separated tags of the filter
+func (f DeviceListFilter) matchesTags(device DeviceSummary) bool {
+	if f.Tags == "" {
+		return true
+	}
+	has := map[string]bool{}
+	for _, tag := range device.tagList() {
+		has[strings.ToLower(tag)] = true
+	}
+	matched := 0
+	wanted := 0
+	for _, tag := range strings.Split(f.Tags, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		wanted++
+		if has[tag] {
+			matched++
+		}
+	}
+	if f.TagMatch == "any" {
+		return matched > 0
+	}
+	return matched == wanted
+}
+
+// List the devices in the project or in a fleet, either a single page or, with all, every
+// page, showing only those that match the filter
+func deviceList(appMetadata AppMetadata, filter DeviceListFilter, all bool, pageSize int, pageNum int, asJSON bool, pretty bool, flagVerbose bool) (err error) {
+
+	if pageSize <= 0 {
+		pageSize = deviceListPageSize
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if filter.TagMatch != "" && filter.TagMatch != "all" && filter.TagMatch != "any" {
+		return fmt.Errorf("-tag-match must be all or any")
+	}
+
+	// Let notehub select the fleet's devices rather than pulling the whole list
+	url := fmt.Sprintf("/v1/projects/%s/devices", appMetadata.App.UID)
+	if filter.Fleet != "" {
+		var fleet Metadata
+		fleet, err = fleetFind(appMetadata, filter.Fleet)
+		if err != nil {
+			return
+		}
+		url = fmt.Sprintf("/v1/projects/%s/fleets/%s/devices", appMetadata.App.UID, fleet.UID)
+	}
+
+	devices := []DeviceSummary{}
+	total := 0
+	hasMore := false
+	addPage := func(rsp DevicesResponse) {
+		for _, device := range rsp.Devices {
+			total++
+			if filter.matches(device) {
+				devices = append(devices, device)
+			}
+		}
+	}
+	if all {
+		err = paginate(url, pageSize, flagVerbose, func(page []byte) error {
+			rsp := DevicesResponse{}
+			err := note.JSONUnmarshal(page, &rsp)
+			addPage(rsp)
+			return err
+		})
+	} else {
+		rsp := DevicesResponse{}
+		url = fmt.Sprintf("%s?pageSize=%d&pageNum=%d", url, pageSize, pageNum)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &rsp)
+		addPage(rsp)
+		hasMore = rsp.HasMore
+	}
+	if err != nil {
+		return
+	}
+
+	err = deviceListShow(devices, asJSON, pretty)
+	if err != nil || asJSON {
+		return
+	}
+	fmt.Printf("%d of %d devices matched\n", len(devices), total)
+	if hasMore {
+		fmt.Printf("showing page %d of results; use -page-num %d for the next page, or -all for every page\n", pageNum, pageNum+1)
+	}
+
+	return
+
+}
+
+// Display a list of devices, one per line or as a JSON array
+func deviceListShow(devices []DeviceSummary, asJSON bool, pretty bool) (err error) {
+
+	if asJSON {
+		var devicesJSON []byte
+		if pretty {
+			devicesJSON, err = note.JSONMarshalIndent(devices, "", "    ")
+		} else {
+			devicesJSON, err = note.JSONMarshal(devices)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", devicesJSON)
+		}
+		return
+	}
+
+	for _, device := range devices {
+		fmt.Printf("%-40s %-24s %s\n", device.UID, device.SerialNumber, device.LastActivity)
+	}
+
+	return
+
+}
+
+// Fields of a device shown in its summary, in display order
+var deviceGetFields = []string{
+	"uid", "Device",
+	"serial_number", "Serial Number",
+	"product_uid", "Product",
+	"sku", "SKU",
+	"fleet_uids", "Fleets",
+	"firmware_notecard", "Notecard Firmware",
+	"firmware_host", "Host Firmware",
+	"provisioned", "Provisioned",
+	"last_activity", "Last Activity",
+	"contact", "Contact",
+	"disabled", "Disabled",
+}
+
+// Resolve a device UID, or a serial number by searching the project's devices
+func deviceResolve(appMetadata AppMetadata, device string, flagVerbose bool) (deviceUID string, err error) {
+	if strings.HasPrefix(device, "dev:") {
+		return device, nil
+	}
+	errFound := fmt.Errorf("found")
+	err = devicesForEach(appMetadata, nil, nil, flagVerbose, func(d DeviceSummary) error {
+		if strings.EqualFold(d.SerialNumber, device) {
+			deviceUID = d.UID
+			return errFound
+		}
+		return nil
+	})
+	if err == errFound {
+		err = nil
+	}
+	if err == nil && deviceUID == "" {
+		err = fmt.Errorf("no device with UID or serial number '%s' found in project", device)
+	}
+	return
+}
+
+// Display the full detail of a single device
+func deviceGet(appMetadata AppMetadata, device string, flagPretty bool, flagJSON bool, flagVerbose bool) (err error) {
+
+	deviceUID, err := deviceResolve(appMetadata, device, flagVerbose)
+	if err != nil {
+		return
+	}
+
+	detail := map[string]interface{}{}
+	url := fmt.Sprintf("/v1/projects/%s/devices/%s", appMetadata.App.UID, deviceUID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &detail)
+	if err != nil {
+		return
+	}
+
+	// The complete record
+	var detailJSON []byte
+	if flagPretty || !flagJSON {
+		detailJSON, err = note.JSONMarshalIndent(detail, "", "    ")
+	} else {
+		detailJSON, err = note.JSONMarshal(detail)
+	}
+	if err != nil {
+		return
+	}
+	if flagJSON {
+		fmt.Printf("%s\n", detailJSON)
+		return
+	}
+
+	// A summary of its metadata, followed by the complete record
+	for i := 0; i < len(deviceGetFields)/2; i++ {
+		v, present := detail[deviceGetFields[i*2]]
+		if !present {
+			continue
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			vJSON, _ := note.JSONMarshal(v)
+			v = string(vJSON)
+		}
+		fmt.Printf("%18s: %v\n", deviceGetFields[i*2+1], v)
+	}
+	fmt.Printf("\n%s\n", detailJSON)
+
+	return
+
+}
+
+// Delete devices from the project, continuing past individual failures
+func deviceDelete(appMetadata AppMetadata, uids []string, confirmed bool, dryRun bool, flagVerbose bool) (err error) {
+
+	if dryRun {
+		for _, deviceUID := range uids {
+			fmt.Printf("(dry run) %s would be deleted\n", deviceUID)
+		}
+		return
+	}
+
+	if !confirmed {
+		return fmt.Errorf("this will permanently delete %d devices from the project; use -yes to confirm", len(uids))
+	}
+
+	deleted := 0
+	for _, deviceUID := range uids {
+
+		url := fmt.Sprintf("/v1/projects/%s/devices/%s", appMetadata.App.UID, deviceUID)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "DELETE", url, nil, nil)
+		if err != nil {
+			fmt.Printf("%s NOT deleted: %s\n", deviceUID, err)
+			continue
+		}
+		deleted++
+		fmt.Printf("%s deleted\n", deviceUID)
+
+	}
+
+	err = nil
+	fmt.Printf("%d of %d devices deleted\n", deleted, len(uids))
+	if deleted != len(uids) {
+		err = fmt.Errorf("%d devices could not be deleted", len(uids)-deleted)
+	}
+
+	return
+
+}
+
+// The version in a device's firmware field, which notehub reports as a JSON object
+// describing the firmware, or as a plain version string
+func deviceFirmwareVersion(firmware string) string {
+	if firmware == "" {
+		return "(unknown)"
+	}
+	info := map[string]interface{}{}
+	if note.JSONUnmarshal([]byte(firmware), &info) == nil {
+		if version, _ := info["version"].(string); version != "" {
+			return version
+		}
+	}
+	return firmware
+}
+
+// Display a histogram of the number of devices running each notecard and host firmware version
+func deviceFirmwareSpread(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	spread := map[string]map[string]int{"notecard": {}, "host": {}}
+	total := 0
+	err = devicesForEach(appMetadata, scopeDevices, scopeFleets, flagVerbose, func(device DeviceSummary) error {
+		total++
+		spread["notecard"][deviceFirmwareVersion(device.FirmwareNotecard)]++
+		spread["host"][deviceFirmwareVersion(device.FirmwareHost)]++
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// Output as JSON if requested
+	if flagJson {
+		var spreadJSON []byte
+		if flagPretty {
+			spreadJSON, err = note.JSONMarshalIndent(spread, "", "    ")
+		} else {
+			spreadJSON, err = note.JSONMarshal(spread)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", spreadJSON)
+		}
+		return
+	}
+
+	// Display an ASCII bar chart per kind of firmware, most common version first
+	barWidth := 40
+	for _, kind := range []string{"notecard", "host"} {
+		counts := spread[kind]
+		versions := []string{}
+		for v := range counts {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			if counts[versions[i]] != counts[versions[j]] {
+				return counts[versions[i]] > counts[versions[j]]
+			}
+			return versions[i] < versions[j]
+		})
+		fmt.Printf("%s firmware:\n", kind)
+		for _, v := range versions {
+			bar := strings.Repeat("#", (counts[v]*barWidth+total-1)/total)
+			fmt.Printf("    %-32s %6d %s\n", v, counts[v], bar)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("%d devices\n", total)
+
+	return
+
+}
+
+// Show the env vars of each device in scope after setting and deleting any that were specified.
+// A scope of fleets applies to every device within them.
+func deviceEnv(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, set []string, remove []string, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	uids := scopeDevices
+	if len(scopeFleets) != 0 {
+		uids = []string{}
+		err = devicesForEach(appMetadata, nil, scopeFleets, flagVerbose, func(device DeviceSummary) error {
+			uids = append(uids, device.UID)
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	if len(uids) == 0 {
+		return fmt.Errorf("no devices found within the specified scope")
+	}
+
+	if len(set) > 0 {
+		var template Vars
+		template, err = varsParseAssignments(set)
+		if err != nil {
+			return
+		}
+		_, err = varsSetFromDevices(appMetadata, uids, template, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+	for _, deviceUID := range uids {
+		if len(remove) > 0 {
+			err = varsDelete(appMetadata, "devices", deviceUID, remove, flagVerbose)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	var vars map[string]Vars
+	vars, err = varsGetFromDevices(appMetadata, uids, flagVerbose)
+	if err != nil {
+		return
+	}
+	return varsShow(vars, flagJson, flagPretty)
+
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// The kinds of firmware whose DFU status may be queried
+var dfuFirmwareTypes = []string{"notecard", "host"}
+
+// DfuStatus is the state of a device's firmware update for a single type of firmware
+type DfuStatus struct {
+	DeviceUID        string `json:"device_uid"`
+	FirmwareType     string `json:"firmware_type"`
+	State            string `json:"state"`
+	Phase            string `json:"phase,omitempty"`
+	CurrentVersion   string `json:"current_version,omitempty"`
+	RequestedVersion string `json:"requested_version,omitempty"`
+}
+
+// Get the DFU status of a device's firmware of the specified type
+func dfuStatusGet(appMetadata AppMetadata, deviceUID string, firmwareType string, flagVerbose bool) (status DfuStatus, err error) {
+
+	rsp := map[string]interface{}{}
+	url := fmt.Sprintf("/v1/projects/%s/devices/%s/dfu/%s/status", appMetadata.App.UID, deviceUID, firmwareType)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &rsp)
+	if err != nil {
+		return
+	}
+
+	status.DeviceUID = deviceUID
+	status.FirmwareType = firmwareType
+	if current, ok := rsp["current"].(map[string]interface{}); ok {
+		status.CurrentVersion, _ = current["version"].(string)
+	}
+	if dfu, ok := rsp["status"].(map[string]interface{}); ok {
+		status.State, _ = dfu["status"].(string)
+		status.Phase, _ = dfu["phase"].(string)
+		status.RequestedVersion, _ = dfu["requested_version"].(string)
+	}
+	if status.State == "" {
+		status.State = "none"
+	}
+
+	return
+
+}
+
+// Show the state of any pending, in-progress, or completed firmware update of each device
+// in scope, for host firmware, notecard firmware, or both
+func dfuStatus(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, firmwareType string, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	types := dfuFirmwareTypes
+	if firmwareType != "" {
+		valid := false
+		for _, t := range dfuFirmwareTypes {
+			if firmwareType == t {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("-dfu-type must be notecard or host")
+		}
+		types = []string{firmwareType}
+	}
+
+	uids := scopeDevices
+	if len(scopeFleets) != 0 {
+		uids = []string{}
+		err = devicesForEach(appMetadata, nil, scopeFleets, flagVerbose, func(device DeviceSummary) error {
+			uids = append(uids, device.UID)
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	if len(uids) == 0 {
+		return fmt.Errorf("no devices found within the specified scope")
+	}
+
+	statuses := []DfuStatus{}
+	for _, deviceUID := range uids {
+		for _, t := range types {
+			var status DfuStatus
+			status, err = dfuStatusGet(appMetadata, deviceUID, t, flagVerbose)
+			if err != nil {
+				return fmt.Errorf("%s: %s", deviceUID, err)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	if flagJson {
+		var statusesJSON []byte
+		if flagPretty {
+			statusesJSON, err = note.JSONMarshalIndent(statuses, "", "    ")
+		} else {
+			statusesJSON, err = note.JSONMarshal(statuses)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", statusesJSON)
+		}
+		return
+	}
+
+	fmt.Printf("%-32s %-8s %-12s %-24s %s\n", "DEVICE", "TYPE", "STATE", "CURRENT", "REQUESTED")
+	for _, s := range statuses {
+		state := s.State
+		if s.Phase != "" && s.Phase != s.State {
+			state += "/" + s.Phase
+		}
+		fmt.Printf("%-32s %-8s %-12s %-24s %s\n", s.DeviceUID, s.FirmwareType, state, s.CurrentVersion, s.RequestedVersion)
+	}
+
+	return
+
+}
+
+// FirmwareInfo describes a firmware file that has been uploaded to the project
+type FirmwareInfo struct {
+	Filename string `json:"filename"`
+	Version  string `json:"version,omitempty"`
+	Type     string `json:"type,omitempty"`
+}
+
+// Number of similarly-named firmware files suggested when a filename isn't found
+const dfuSuggestionsMax = 5
+
+// The number of single-character edits needed to turn one string into another
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Verify that a firmware file of the specified type has been uploaded to the project,
+// suggesting the most similarly-named files if it hasn't
+func dfuValidateFilename(appMetadata AppMetadata, firmwareType string, filename string, flagVerbose bool) (err error) {
+
+	files := []FirmwareInfo{}
+	url := fmt.Sprintf("/v1/projects/%s/firmware?firmwareType=%s", appMetadata.App.UID, firmwareType)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &files)
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		if f.Filename == filename {
+			return
+		}
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no %s firmware has been uploaded to the project", firmwareType)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return editDistance(filename, files[i].Filename) < editDistance(filename, files[j].Filename)
+	})
+	fmt.Printf("%s firmware files most similar to %s:\n", firmwareType, filename)
+	for i, f := range files {
+		if i >= dfuSuggestionsMax {
+			break
+		}
+		fmt.Printf("    %s %s\n", f.Filename, f.Version)
+	}
+	return fmt.Errorf("%s is not a %s firmware file in this project (use -force to schedule it anyway)", filename, firmwareType)
+
+}
+
+// Schedule an update of the devices or fleets in scope to the specified firmware file,
+// first verifying that the file exists unless forced
+func dfuUpdate(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, firmwareType string, filename string, force bool, dryRun bool, flagVerbose bool) (err error) {
+
+	if firmwareType != "notecard" && firmwareType != "host" {
+		return fmt.Errorf("use -dfu-type notecard or host to specify the type of firmware being updated")
+	}
+
+	if !force {
+		err = dfuValidateFilename(appMetadata, firmwareType, filename, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+
+	targets := scopeDevices
+	param := "deviceUID"
+	if len(scopeFleets) != 0 {
+		targets = scopeFleets
+		param = "fleetUID"
+	}
+
+	for _, target := range targets {
+		if dryRun {
+			fmt.Printf("(dry run) %s would be updated to %s firmware %s\n", target, firmwareType, filename)
+			continue
+		}
+		url := fmt.Sprintf("/v1/projects/%s/dfu/%s/update?%s=%s", appMetadata.App.UID, firmwareType, param, target)
+		_, err = projectPost("POST", url, map[string]interface{}{"filename": filename}, flagVerbose)
+		if err != nil {
+			return fmt.Errorf("%s: %s", target, err)
+		}
+		fmt.Printf("%s scheduled for update to %s firmware %s\n", target, firmwareType, filename)
+	}
+
+	return
+
+}
@@ -0,0 +1,327 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// Event is the subset of a notehub event that we make use of
+type Event struct {
+	EventUID   string                  `json:"event,omitempty"`
+	DeviceUID  string                  `json:"device,omitempty"`
+	SN         string                  `json:"sn,omitempty"`
+	NotefileID string                  `json:"file,omitempty"`
+	NoteID     string                  `json:"note,omitempty"`
+	When       int64                   `json:"when,omitempty"`
+	Received   float64                 `json:"received,omitempty"`
+	Body       *map[string]interface{} `json:"body,omitempty"`
+}
+
+// EventsResponse is the response to an events query
+type EventsResponse struct {
+	Events  []Event `json:"events,omitempty"`
+	HasMore bool    `json:"has_more,omitempty"`
+}
+
+// EventBucket is a single bucket of an events histogram
+type EventBucket struct {
+	Begin string `json:"begin"`
+	Count int    `json:"count"`
+}
+
+// Parse a duration, additionally allowing a 'd' suffix for days
+func parseDuration(s string) (d time.Duration, err error) {
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			err = fmt.Errorf("invalid duration: %s", s)
+			return
+		}
+		d = time.Duration(days) * 24 * time.Hour
+		return
+	}
+	return time.ParseDuration(s)
+}
+
+// Received time of an event, falling back to the device's capture time
+func (e Event) Time() time.Time {
+	if e.Received != 0 {
+		return time.Unix(int64(e.Received), 0).UTC()
+	}
+	return time.Unix(e.When, 0).UTC()
+}
+
+// Get the events for a set of devices or fleets since the specified time
+func eventsGet(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, since time.Time, flagVerbose bool) (events []Event, err error) {
+
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("startDate", fmt.Sprintf("%d", since.Unix()))
+	}
+	for _, deviceUID := range scopeDevices {
+		query.Add("deviceUID", deviceUID)
+	}
+	for _, fleetUID := range scopeFleets {
+		query.Add("fleetUID", fleetUID)
+	}
+	query.Set("sortBy", "captured")
+	query.Set("sortOrder", "asc")
+
+	url := fmt.Sprintf("/v1/projects/%s/events?%s", appMetadata.App.UID, query.Encode())
+	err = paginate(url, 500, flagVerbose, func(page []byte) error {
+		rsp := EventsResponse{}
+		err := note.JSONUnmarshal(page, &rsp)
+		events = append(events, rsp.Events...)
+		return err
+	})
+
+	return
+
+}
+
+// Count events into buckets of the specified width, beginning at the specified time
+func eventsHistogram(events []Event, since time.Time, bucket time.Duration) (buckets []EventBucket) {
+
+	// Determine the range of the histogram
+	begin := since.Truncate(bucket)
+	end := time.Now().UTC()
+	if len(events) > 0 && since.IsZero() {
+		begin = events[0].Time()
+		for _, e := range events {
+			if e.Time().Before(begin) {
+				begin = e.Time()
+			}
+		}
+		begin = begin.Truncate(bucket)
+	}
+
+	// Count the events
+	counts := map[int64]int{}
+	for _, e := range events {
+		counts[int64(e.Time().Sub(begin)/bucket)]++
+	}
+
+	// Generate all the buckets, including empty ones so that gaps are visible
+	for i := int64(0); begin.Add(time.Duration(i) * bucket).Before(end); i++ {
+		b := EventBucket{}
+		b.Begin = begin.Add(time.Duration(i) * bucket).Format("2006-01-02T15:04:05Z")
+		b.Count = counts[i]
+		buckets = append(buckets, b)
+	}
+
+	return
+
+}
+
+// Display a histogram of event counts over time for devices or fleets
+func eventsCount(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, flagBucket string, flagSince string, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	// Parse the time parameters
+	bucket := time.Hour
+	if flagBucket != "" {
+		bucket, err = parseDuration(flagBucket)
+		if err != nil {
+			return
+		}
+		if bucket <= 0 {
+			return fmt.Errorf("bucket width must be positive")
+		}
+	}
+	since := time.Now().UTC().Add(-7 * 24 * time.Hour)
+	if flagSince != "" {
+		var d time.Duration
+		d, err = parseDuration(flagSince)
+		if err != nil {
+			return
+		}
+		since = time.Now().UTC().Add(-d)
+	}
+
+	// Fetch the events
+	var events []Event
+	events, err = eventsGet(appMetadata, scopeDevices, scopeFleets, since, flagVerbose)
+	if err != nil {
+		return
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Time().Before(events[j].Time()) })
+	buckets := eventsHistogram(events, since, bucket)
+
+	// Output as JSON if requested
+	if flagJson {
+		var bucketsJSON []byte
+		if flagPretty {
+			bucketsJSON, err = note.JSONMarshalIndent(buckets, "", "    ")
+		} else {
+			bucketsJSON, err = note.JSONMarshal(buckets)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", bucketsJSON)
+		}
+		return
+	}
+
+	// Display an ASCII bar chart scaled to the largest bucket
+	maxCount := 0
+	for _, b := range buckets {
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+	barWidth := 50
+	for _, b := range buckets {
+		bar := ""
+		if maxCount > 0 {
+			bar = strings.Repeat("#", (b.Count*barWidth+maxCount-1)/maxCount)
+		}
+		fmt.Printf("%s %6d %s\n", b.Begin, b.Count, bar)
+	}
+	fmt.Printf("%d events in %d buckets of %s\n", len(events), len(buckets), bucket)
+
+	return
+
+}
+
+// Fields of an event shown in its summary, in display order
+var eventGetFields = []string{
+	"event", "Event",
+	"device", "Device",
+	"sn", "Serial Number",
+	"product", "Product",
+	"file", "Notefile",
+	"note", "Note",
+	"when", "Captured",
+	"received", "Received",
+	"routed", "Routed",
+	"tower_location", "Tower Location",
+	"best_location", "Best Location",
+}
+
+// Display the full detail of a single event
+func eventGet(appMetadata AppMetadata, eventUID string, flagPretty bool, flagJSON bool, flagVerbose bool) (err error) {
+
+	event := map[string]interface{}{}
+	url := fmt.Sprintf("/v1/projects/%s/events/%s", appMetadata.App.UID, eventUID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &event)
+	if err != nil {
+		return
+	}
+
+	// The complete record
+	var eventJSON []byte
+	if flagPretty || !flagJSON {
+		eventJSON, err = note.JSONMarshalIndent(event, "", "    ")
+	} else {
+		eventJSON, err = note.JSONMarshal(event)
+	}
+	if err != nil {
+		return
+	}
+	if flagJSON {
+		fmt.Printf("%s\n", eventJSON)
+		return
+	}
+
+	// A summary of its metadata, followed by the complete record
+	for i := 0; i < len(eventGetFields)/2; i++ {
+		v, present := event[eventGetFields[i*2]]
+		if !present {
+			continue
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			vJSON, _ := note.JSONMarshal(v)
+			v = string(vJSON)
+		}
+		fmt.Printf("%16s: %v\n", eventGetFields[i*2+1], v)
+	}
+	if payload, _ := event["payload"].(string); payload != "" {
+		decoded, err2 := base64.StdEncoding.DecodeString(payload)
+		if err2 == nil {
+			fmt.Printf("%16s: %d bytes\n%s\n", "Payload", len(decoded), decoded)
+		}
+	}
+	fmt.Printf("\n%s\n", eventJSON)
+
+	return
+
+}
+
+// Maximum width of an event body summary
+const eventsListBodyWidth = 80
+
+// Display the recent events of devices or fleets, one per line, optionally restricted to a
+// notefile and to the most recent limit events
+func eventsList(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, flagSince string, notefileID string, limit int, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	since := time.Now().UTC().Add(-24 * time.Hour)
+	if flagSince != "" {
+		var d time.Duration
+		d, err = parseDuration(flagSince)
+		if err != nil {
+			return
+		}
+		since = time.Now().UTC().Add(-d)
+	}
+
+	var events []Event
+	events, err = eventsGet(appMetadata, scopeDevices, scopeFleets, since, flagVerbose)
+	if err != nil {
+		return
+	}
+	if notefileID != "" {
+		matched := []Event{}
+		for _, e := range events {
+			if e.NotefileID == notefileID {
+				matched = append(matched, e)
+			}
+		}
+		events = matched
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Time().Before(events[j].Time()) })
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+
+	// Output as JSON if requested
+	if flagJson {
+		var eventsJSON []byte
+		if flagPretty {
+			eventsJSON, err = note.JSONMarshalIndent(events, "", "    ")
+		} else {
+			eventsJSON, err = note.JSONMarshal(events)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", eventsJSON)
+		}
+		return
+	}
+
+	for _, e := range events {
+		body := ""
+		if e.Body != nil {
+			bodyJSON, _ := note.JSONMarshal(e.Body)
+			body = string(bodyJSON)
+			if len(body) > eventsListBodyWidth {
+				body = body[:eventsListBodyWidth-3] + "..."
+			}
+		}
+		fmt.Printf("%s %-16s %-40s %s\n", e.Time().Format("2006-01-02T15:04:05Z"), e.NotefileID, e.DeviceUID, body)
+	}
+	fmt.Printf("%d events\n", len(events))
+
+	return
+
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// Fleet is a fleet as returned by the notehub API
+type Fleet struct {
+	UID   string `json:"uid,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+// DeviceFleetsResponse is the response to a query of the fleets that a device is in
+type DeviceFleetsResponse struct {
+	Fleets []Fleet `json:"fleets,omitempty"`
+}
+
+// DeviceFleetsRequest is the request used to add or remove a device from fleets
+type DeviceFleetsRequest struct {
+	FleetUIDs []string `json:"fleet_uids"`
+}
+
+// Find a fleet within the app by UID or by name
+func fleetFind(appMetadata AppMetadata, fleet string) (found Metadata, err error) {
+	for _, f := range appMetadata.Fleets {
+		if f.UID == fleet || strings.EqualFold(f.Name, fleet) {
+			return f, nil
+		}
+	}
+	err = fmt.Errorf("fleet '%s' not found in project", fleet)
+	return
+}
+
+// Get the fleets that a device is currently a member of
+func fleetsOfDevice(appMetadata AppMetadata, deviceUID string, flagVerbose bool) (fleets []Fleet, err error) {
+	rsp := DeviceFleetsResponse{}
+	url := fmt.Sprintf("/v1/projects/%s/devices/%s/fleets", appMetadata.App.UID, deviceUID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &rsp)
+	fleets = rsp.Fleets
+	return
+}
+
+// Add or remove a device from a set of fleets
+func fleetsUpdateDevice(appMetadata AppMetadata, deviceUID string, verb string, fleetUIDs []string, flagVerbose bool) (err error) {
+	var reqJSON []byte
+	reqJSON, err = note.JSONMarshal(DeviceFleetsRequest{FleetUIDs: fleetUIDs})
+	if err != nil {
+		return
+	}
+	url := fmt.Sprintf("/v1/projects/%s/devices/%s/fleets", appMetadata.App.UID, deviceUID)
+	return reqHubV1(flagVerbose, lib.ConfigAPIHub(), verb, url, reqJSON, nil)
+}
+
+// Move devices into a fleet, optionally removing them from all of their other fleets
+func fleetMoveDevices(appMetadata AppMetadata, uids []string, target string, addOnly bool, dryRun bool, flagVerbose bool) (err error) {
+
+	// Find the target fleet
+	var targetFleet Metadata
+	targetFleet, err = fleetFind(appMetadata, target)
+	if err != nil {
+		return
+	}
+
+	for _, deviceUID := range uids {
+
+		// Determine current membership
+		var fleets []Fleet
+		fleets, err = fleetsOfDevice(appMetadata, deviceUID, flagVerbose)
+		if err != nil {
+			return
+		}
+		current := []string{}
+		remove := []string{}
+		alreadyMember := false
+		for _, f := range fleets {
+			current = append(current, fmt.Sprintf("%s (%s)", f.Label, f.UID))
+			if f.UID == targetFleet.UID {
+				alreadyMember = true
+			} else {
+				remove = append(remove, f.UID)
+			}
+		}
+		if addOnly {
+			remove = []string{}
+		}
+
+		// Preview the change
+		currentDesc := strings.Join(current, ", ")
+		if currentDesc == "" {
+			currentDesc = "(none)"
+		}
+		action := "move to"
+		if addOnly {
+			action = "add to"
+		}
+		if alreadyMember && len(remove) == 0 {
+			action = "already in"
+		}
+		prefix := ""
+		if dryRun {
+			prefix = "(dry run) "
+		}
+		fmt.Printf("%s%s: currently in %s; %s %s (%s)\n", prefix, deviceUID, currentDesc, action, targetFleet.Name, targetFleet.UID)
+		if dryRun {
+			continue
+		}
+
+		// Add to the target before removing so that the device is never fleet-less
+		if !alreadyMember {
+			err = fleetsUpdateDevice(appMetadata, deviceUID, "PUT", []string{targetFleet.UID}, flagVerbose)
+			if err != nil {
+				return
+			}
+		}
+		if len(remove) > 0 {
+			err = fleetsUpdateDevice(appMetadata, deviceUID, "DELETE", remove, flagVerbose)
+			if err != nil {
+				return
+			}
+		}
+
+	}
+
+	return
+
+}
+
+// FleetWatchdog is the connectivity assurance configuration of a fleet
+type FleetWatchdog struct {
+	UID          string `json:"uid,omitempty"`
+	Label        string `json:"label,omitempty"`
+	WatchdogMins int64  `json:"watchdog_mins,omitempty"`
+}
+
+// Report which devices in a fleet have not been heard from within the fleet's watchdog window
+func fleetWatchdog(appMetadata AppMetadata, fleet string, flagVerbose bool) (err error) {
+
+	var f Metadata
+	f, err = fleetFind(appMetadata, fleet)
+	if err != nil {
+		return
+	}
+	watchdog := FleetWatchdog{}
+	url := fmt.Sprintf("/v1/projects/%s/fleets/%s", appMetadata.App.UID, f.UID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &watchdog)
+	if err != nil {
+		return
+	}
+	if watchdog.WatchdogMins == 0 {
+		return fmt.Errorf("fleet '%s' has no watchdog configured", f.Name)
+	}
+	window := time.Duration(watchdog.WatchdogMins) * time.Minute
+
+	total := 0
+	overdue := 0
+	now := time.Now().UTC()
+	fmt.Printf("fleet '%s' watchdog is %d minutes\n\n", f.Name, watchdog.WatchdogMins)
+	err = devicesForEach(appMetadata, nil, []string{f.UID}, flagVerbose, func(device DeviceSummary) error {
+		total++
+		status := "ok"
+		lastActivity := device.LastActivity
+		last, err := time.Parse(time.RFC3339, device.LastActivity)
+		if err != nil {
+			status = "OVERDUE (never seen)"
+			lastActivity = "-"
+			overdue++
+		} else if since := now.Sub(last); since > window {
+			status = fmt.Sprintf("OVERDUE by %s", (since - window).Truncate(time.Minute))
+			overdue++
+		}
+		fmt.Printf("%-40s %-16s %-22s %s\n", device.UID, device.SerialNumber, lastActivity, status)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	fmt.Printf("\n%d of %d devices are overdue\n", overdue, total)
+
+	return
+
+}
+
+// List the devices that are members of a fleet
+func fleetDevices(appMetadata AppMetadata, fleet string, countOnly bool, asJSON bool, pretty bool, flagVerbose bool) (err error) {
+
+	var f Metadata
+	f, err = fleetFind(appMetadata, fleet)
+	if err != nil {
+		return
+	}
+
+	devices := []DeviceSummary{}
+	err = devicesForEach(appMetadata, nil, []string{f.UID}, flagVerbose, func(device DeviceSummary) error {
+		devices = append(devices, device)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if countOnly {
+		if asJSON {
+			fmt.Printf("{\"fleet\":\"%s\",\"count\":%d}\n", f.UID, len(devices))
+		} else {
+			fmt.Printf("%d\n", len(devices))
+		}
+		return
+	}
+
+	err = deviceListShow(devices, asJSON, pretty)
+	if err == nil && !asJSON {
+		fmt.Printf("%d devices in fleet '%s'\n", len(devices), f.Name)
+	}
+
+	return
+
+}
+
+// Show a fleet's env vars after setting and deleting any that were specified
+func fleetEnv(appMetadata AppMetadata, fleet string, set []string, remove []string, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	var f Metadata
+	f, err = fleetFind(appMetadata, fleet)
+	if err != nil {
+		return
+	}
+
+	if len(set) > 0 {
+		var template Vars
+		template, err = varsParseAssignments(set)
+		if err != nil {
+			return
+		}
+		_, err = varsSetFromFleets(appMetadata, []string{f.UID}, template, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+	if len(remove) > 0 {
+		err = varsDelete(appMetadata, "fleets", f.UID, remove, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+
+	var vars map[string]Vars
+	vars, err = varsGetFromFleets(appMetadata, []string{f.UID}, flagVerbose)
+	if err != nil {
+		return
+	}
+	return varsShow(map[string]Vars{f.Name: vars[f.UID]}, flagJson, flagPretty)
+
+}
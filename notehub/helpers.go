@@ -0,0 +1,91 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// A flag that may be specified multiple times on the command line
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// The pagination fields common to all notehub list responses
+type pageInfo struct {
+	HasMore bool `json:"has_more,omitempty"`
+}
+
+// Returned by a paginate callback to stop paging, without error, once it has seen enough
+var errPaginateStop = errors.New("stop paging")
+
+// Fetch every page of a paginated V1 list endpoint, invoking fn with the JSON of each page
+// until the service indicates that there are no more pages.  Endpoints that return a bare
+// array rather than an object have more pages for as long as each page is full.
+func paginate(url string, pageSize int, flagVerbose bool, fn func(page []byte) error) (err error) {
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+
+	pageNum := 0
+	for {
+		pageNum++
+
+		var page []byte
+		pageURL := fmt.Sprintf("%s%spageSize=%d&pageNum=%d", url, separator, pageSize, pageNum)
+		page, err = reqHubV1JSON(flagVerbose, lib.ConfigAPIHub(), "GET", pageURL, nil)
+		if err != nil {
+			return
+		}
+
+		err = fn(page)
+		if err == errPaginateStop {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		if bytes.HasPrefix(bytes.TrimSpace(page), []byte("[")) {
+			var items []interface{}
+			err = note.JSONUnmarshal(page, &items)
+			if err != nil {
+				return
+			}
+			if len(items) < pageSize {
+				break
+			}
+			continue
+		}
+
+		var info pageInfo
+		err = note.JSONUnmarshal(page, &info)
+		if err != nil {
+			return
+		}
+		if !info.HasMore {
+			break
+		}
+
+	}
+
+	return
+
+}
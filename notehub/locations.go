@@ -0,0 +1,210 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blues/note-go/note"
+)
+
+// DeviceLocation is a location of a device as known by notehub
+type DeviceLocation struct {
+	When      string  `json:"when,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+}
+
+// DeviceSummary is the subset of a device as returned in notehub device lists that we make use of
+type DeviceSummary struct {
+	UID                  string          `json:"uid,omitempty"`
+	SerialNumber         string          `json:"serial_number,omitempty"`
+	LastActivity         string          `json:"last_activity,omitempty"`
+	GPSLocation          *DeviceLocation `json:"gps_location,omitempty"`
+	TriangulatedLocation *DeviceLocation `json:"triangulated_location,omitempty"`
+	TowerLocation        *DeviceLocation `json:"tower_location,omitempty"`
+	SKU                  string          `json:"sku,omitempty"`
+	FirmwareNotecard     string          `json:"firmware_notecard,omitempty"`
+	FirmwareHost         string          `json:"firmware_host,omitempty"`
+	Tags                 interface{}     `json:"tags,omitempty"`
+}
+
+// The tags of a device, which notehub may report either as a list or as a comma-separated string
+func (d DeviceSummary) tagList() (tags []string) {
+	switch v := d.Tags.(type) {
+	case string:
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	case []interface{}:
+		for _, tag := range v {
+			if s, ok := tag.(string); ok && s != "" {
+				tags = append(tags, s)
+			}
+		}
+	}
+	return
+}
+
+// DevicesResponse is a page of a device list
+type DevicesResponse struct {
+	Devices []DeviceSummary `json:"devices,omitempty"`
+	HasMore bool            `json:"has_more,omitempty"`
+}
+
+// The best known location of a device, preferring GPS over triangulation over cell tower
+func (d DeviceSummary) bestLocation() (location *DeviceLocation, source string) {
+	if d.GPSLocation != nil && (d.GPSLocation.Latitude != 0 || d.GPSLocation.Longitude != 0) {
+		return d.GPSLocation, "gps"
+	}
+	if d.TriangulatedLocation != nil && (d.TriangulatedLocation.Latitude != 0 || d.TriangulatedLocation.Longitude != 0) {
+		return d.TriangulatedLocation, "triangulated"
+	}
+	if d.TowerLocation != nil && (d.TowerLocation.Latitude != 0 || d.TowerLocation.Longitude != 0) {
+		return d.TowerLocation, "tower"
+	}
+	return nil, ""
+}
+
+// Page through the devices in scope, invoking fn for each.  If fleets are in scope, their
+// devices are listed; otherwise all devices are listed, filtered to those in scope if any.
+func devicesForEach(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, flagVerbose bool, fn func(device DeviceSummary) error) (err error) {
+
+	inScope := map[string]bool{}
+	for _, deviceUID := range scopeDevices {
+		inScope[deviceUID] = true
+	}
+
+	forEachInPage := func(page []byte) error {
+		devices := DevicesResponse{}
+		err := note.JSONUnmarshal(page, &devices)
+		if err != nil {
+			return err
+		}
+		for _, device := range devices.Devices {
+			if len(inScope) == 0 || inScope[device.UID] {
+				err = fn(device)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if len(scopeFleets) == 0 {
+		url := fmt.Sprintf("/v1/projects/%s/devices", appMetadata.App.UID)
+		return paginate(url, 500, flagVerbose, forEachInPage)
+	}
+
+	for _, fleetUID := range scopeFleets {
+		url := fmt.Sprintf("/v1/projects/%s/fleets/%s/devices", appMetadata.App.UID, fleetUID)
+		err = paginate(url, 100, flagVerbose, forEachInPage)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+
+}
+
+// Export the last known location of each device in scope as GeoJSON or KML, writing
+// each device's feature as it is paged in from notehub
+func locationsExport(appMetadata AppMetadata, scopeDevices []string, scopeFleets []string, format string, outfile string, flagVerbose bool) (err error) {
+
+	// Open the output
+	var w io.Writer = os.Stdout
+	if outfile != "" {
+		var f *os.File
+		f, err = os.Create(outfile)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// Write the header
+	switch format {
+	case "", "geojson":
+		format = "geojson"
+		fmt.Fprintf(w, "{\"type\":\"FeatureCollection\",\"features\":[\n")
+	case "kml":
+		fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+		fmt.Fprintf(w, "<kml xmlns=\"http://www.opengis.net/kml/2.2\">\n<Document>\n")
+	default:
+		return fmt.Errorf("export format must be geojson or kml")
+	}
+
+	// Write a point per device that has a known location
+	exported := 0
+	skipped := 0
+	err = devicesForEach(appMetadata, scopeDevices, scopeFleets, flagVerbose, func(device DeviceSummary) error {
+		location, source := device.bestLocation()
+		if location == nil {
+			skipped++
+			return nil
+		}
+		label := device.UID
+		if device.SerialNumber != "" {
+			label = device.SerialNumber + " (" + device.UID + ")"
+		}
+		if format == "kml" {
+			var name, desc strings.Builder
+			xml.EscapeText(&name, []byte(label))
+			xml.EscapeText(&desc, []byte(fmt.Sprintf("%s location %s as of %s", source, location.Name, location.When)))
+			fmt.Fprintf(w, "<Placemark><name>%s</name><description>%s</description><Point><coordinates>%f,%f</coordinates></Point></Placemark>\n",
+				name.String(), desc.String(), location.Longitude, location.Latitude)
+		} else {
+			feature := map[string]interface{}{
+				"type": "Feature",
+				"geometry": map[string]interface{}{
+					"type":        "Point",
+					"coordinates": []float64{location.Longitude, location.Latitude},
+				},
+				"properties": map[string]interface{}{
+					"device": device.UID,
+					"sn":     device.SerialNumber,
+					"name":   label,
+					"source": source,
+					"where":  location.Name,
+					"when":   location.When,
+				},
+			}
+			featureJSON, err := note.JSONMarshal(feature)
+			if err != nil {
+				return err
+			}
+			if exported > 0 {
+				fmt.Fprintf(w, ",\n")
+			}
+			w.Write(featureJSON)
+		}
+		exported++
+		return nil
+	})
+
+	// Write the trailer, even on error, so that what was written is well-formed
+	if format == "kml" {
+		fmt.Fprintf(w, "</Document>\n</kml>\n")
+	} else {
+		fmt.Fprintf(w, "\n]}\n")
+	}
+
+	if outfile != "" {
+		fmt.Printf("%d devices exported to %s (%d without a known location)\n", exported, outfile, skipped)
+	}
+
+	return
+
+}
@@ -23,6 +23,10 @@ const exitFail = 1
 var flagApp string
 var flagProduct string
 var flagDevice string
+var flagRequestID string
+
+// Used by app.go
+var flagScopeColumn string
 
 // CLI Version - Set by ldflags during build/release
 var version = "development"
@@ -55,31 +59,178 @@ func main() {
 	flag.BoolVar(&flagSignIn, "signin", false, "sign-in to the notehub so that API requests may be made")
 	var flagSignInToken string
 	flag.StringVar(&flagSignInToken, "signin-token", "", "sign-in to the notehub with an explicit token")
+	var flagAutoReauth bool
+	flag.BoolVar(&flagAutoReauth, "auto-reauth", false, "sign in again with the NOTEHUB_TOKEN personal access token before proceeding if the notehub session is about to expire")
 	var flagSignOut bool
 	flag.BoolVar(&flagSignOut, "signout", false, "sign out of the notehub")
 	var flagToken bool
 	flag.BoolVar(&flagToken, "token", false, "obtain the signed-in account's Authentication Token")
+	var flagWhoAmI bool
+	flag.BoolVar(&flagWhoAmI, "whoami", false, "show the account that is signed in and verify that its token is still valid")
 	var flagExplore bool
 	flag.BoolVar(&flagExplore, "explore", false, "explore the contents of the device")
 	var flagReserved bool
 	flag.BoolVar(&flagReserved, "reserved", false, "when exploring, include reserved notefiles")
 	var flagVerbose bool
 	flag.BoolVar(&flagVerbose, "verbose", false, "display requests and responses")
-	flag.StringVar(&flagApp, "project", "", "projectUID")
+	flag.StringVar(&flagApp, "project", "", "projectUID, productUID, or project name")
 	flag.StringVar(&flagProduct, "product", "", "productUID")
 	flag.StringVar(&flagDevice, "device", "", "deviceUID")
+	flag.StringVar(&flagRequestID, "request-id", "", "use this X-Request-ID on all HTTP requests rather than a unique one per request")
 	var flagVersion bool
 	flag.BoolVar(&flagVersion, "version", false, "print the current version of the CLI")
 	var flagScope string
-	flag.StringVar(&flagScope, "scope", "", "dev:xx or @fleet:xx or fleet:xx or @filename")
+	flag.StringVar(&flagScope, "scope", "", "dev:xx or @fleet:xx or fleet:xx or @filename, comma-separated, with !dev:xx or !fleet:xx to exclude")
+	flag.StringVar(&flagScopeColumn, "scope-column", "device_uid", "with a -scope of @filename.csv, the name of the column containing the devices")
 	var flagVarsGet bool
 	flag.BoolVar(&flagVarsGet, "get-vars", false, "get environment vars")
+	var flagTable bool
+	flag.BoolVar(&flagTable, "table", false, "with -get-vars, display the vars as a table with a column per device or fleet")
 	var flagVarsSet string
 	flag.StringVar(&flagVarsSet, "set-vars", "", "set environment vars using a json template")
 	var flagSn string
 	flag.StringVar(&flagSn, "sn", "", "serial number")
 	var flagProvision bool
 	flag.BoolVar(&flagProvision, "provision", false, "provision devices")
+	var flagMoveToFleet string
+	flag.StringVar(&flagMoveToFleet, "move-to-fleet", "", "move the devices in -scope into the specified fleet, removing them from their other fleets")
+	var flagAdd bool
+	flag.BoolVar(&flagAdd, "add", false, "with -move-to-fleet, add devices to the fleet without removing them from their other fleets")
+	var flagDryRun bool
+	flag.BoolVar(&flagDryRun, "dry-run", false, "with -move-to-fleet, -enable, -disable, -delete, -factory-reset, or -dfu-update, show the devices that would be changed without changing them")
+	var flagRouteSimulate string
+	flag.StringVar(&flagRouteSimulate, "route-simulate", "", "deliver the event in -input to the target of the specified route from this host")
+	var flagRouteLogs string
+	flag.StringVar(&flagRouteLogs, "route-logs", "", "show the delivery logs of the specified route over the -since period (default 1d)")
+	var flagAggregateErrors bool
+	flag.BoolVar(&flagAggregateErrors, "aggregate-errors", false, "with -route-logs, group failed deliveries by error, with counts and first/last occurrence")
+	var flagRouteHeaders string
+	flag.StringVar(&flagRouteHeaders, "route-headers", "", "show the custom HTTP headers of the specified route")
+	var flagSetHeader multiFlag
+	flag.Var(&flagSetHeader, "set-header", "with -route-headers, set a header as KEY:VALUE (may be repeated)")
+	var flagDeleteHeader multiFlag
+	flag.Var(&flagDeleteHeader, "delete-header", "with -route-headers, delete the header KEY (may be repeated)")
+	var flagRouteCreate string
+	flag.StringVar(&flagRouteCreate, "route-create", "", "create a route with this name from -config and/or -route-type, -url, -throttle-ms, -route-timeout, and -fleet")
+	var flagConfig string
+	flag.StringVar(&flagConfig, "config", "", "with -route-create, a JSON file containing the route's configuration")
+	var flagRouteType string
+	flag.StringVar(&flagRouteType, "route-type", "", "with -route-create, the type of route such as http")
+	var flagURL string
+	flag.StringVar(&flagURL, "url", "", "with -route-create, the URL of the route's target")
+	var flagThrottleMs int
+	flag.IntVar(&flagThrottleMs, "throttle-ms", 0, "with -route-create, the minimum milliseconds between deliveries")
+	var flagRouteTimeout int
+	flag.IntVar(&flagRouteTimeout, "route-timeout", 0, "with -route-create, the seconds to wait for the route's target to respond")
+	var flagRouteTest string
+	flag.StringVar(&flagRouteTest, "route-test", "", "add a test event with -body to _test.qo of -device and report its delivery by the specified route")
+	var flagBody string
+	flag.StringVar(&flagBody, "body", "", "with -route-test, the JSON body of the test event")
+	var flagRouteEnable string
+	flag.StringVar(&flagRouteEnable, "route-enable", "", "enable the specified route")
+	var flagRouteDisable string
+	flag.StringVar(&flagRouteDisable, "route-disable", "", "disable the specified route")
+	var flagRouteDisableAll bool
+	flag.BoolVar(&flagRouteDisableAll, "route-disable-all", false, "disable every route in the project, remembering which had been enabled (requires -yes)")
+	var flagRouteEnableAll bool
+	flag.BoolVar(&flagRouteEnableAll, "route-enable-all", false, "enable every route in the project (requires -yes)")
+	var flagRestore bool
+	flag.BoolVar(&flagRestore, "restore", false, "with -route-enable-all, re-enable only the routes that were enabled before -route-disable-all")
+	var flagInput string
+	flag.StringVar(&flagInput, "input", "", "input filename")
+	var flagLive bool
+	flag.BoolVar(&flagLive, "live", false, "with -route-simulate, actually contact the route's external target")
+	var flagDisable bool
+	flag.BoolVar(&flagDisable, "disable", false, "disable the devices in -scope")
+	var flagEnable bool
+	flag.BoolVar(&flagEnable, "enable", false, "enable the devices in -scope")
+	var flagDeviceGet string
+	flag.StringVar(&flagDeviceGet, "device-get", "", "show the full detail of the device with this UID or serial number")
+	var flagListDevices bool
+	flag.BoolVar(&flagListDevices, "list-devices", false, "list the devices in the project, one page at a time unless -all is specified")
+	var flagAll bool
+	flag.BoolVar(&flagAll, "all", false, "with -list-devices, fetch every page of devices")
+	var flagPageSize int
+	flag.IntVar(&flagPageSize, "page-size", deviceListPageSize, "with -list-devices, the number of devices per page")
+	var flagPageNum int
+	flag.IntVar(&flagPageNum, "page-num", 1, "with -list-devices, the page of devices to show")
+	var flagListFilter DeviceListFilter
+	flag.StringVar(&flagListFilter.Fleet, "fleet", "", "with -list-devices, list only the devices in this fleet; with -route-create, route only events from this fleet")
+	flag.StringVar(&flagListFilter.SKU, "sku", "", "with -list-devices, list only devices whose SKU contains this")
+	flag.StringVar(&flagListFilter.NotecardFirmware, "notecard-firmware", "", "with -list-devices, list only devices whose notecard firmware contains this version")
+	flag.StringVar(&flagListFilter.HostFirmware, "host-firmware", "", "with -list-devices, list only devices whose host firmware contains this version")
+	flag.StringVar(&flagListFilter.Tags, "tag", "", "with -list-devices, list only devices carrying these comma-separated tags")
+	flag.StringVar(&flagListFilter.TagMatch, "tag-match", "all", "with -tag, whether devices must carry all of the tags or any of them")
+	var flagDelete bool
+	flag.BoolVar(&flagDelete, "delete", false, "delete the devices in -scope from the project (requires -yes)")
+	var flagBulkEnable string
+	flag.StringVar(&flagBulkEnable, "bulk-enable", "", "enable the devices listed in this CSV of device,fleet, moving each to its fleet, writing results as CSV to -out")
+	var flagFactoryReset bool
+	flag.BoolVar(&flagFactoryReset, "factory-reset", false, "queue a factory reset of the notecards of the devices in -scope, performed when each next syncs")
+	var flagYes bool
+	flag.BoolVar(&flagYes, "yes", false, "confirm a destructive action")
+	var flagReason string
+	flag.StringVar(&flagReason, "reason", "", "with -enable, -disable, or -factory-reset, the reason to record in the device's audit log")
+	var flagAccountFleets bool
+	flag.BoolVar(&flagAccountFleets, "account-fleets", false, "list the fleets of every project accessible to this account")
+	var flagMembers bool
+	flag.BoolVar(&flagMembers, "members", false, "list the members of -project and their roles")
+	var flagMemberAdd string
+	flag.StringVar(&flagMemberAdd, "member-add", "", "add the user with this email to -project, with the role in -role")
+	var flagMemberRemove string
+	flag.StringVar(&flagMemberRemove, "member-remove", "", "remove the user with this email from -project (requires -yes)")
+	var flagRole string
+	flag.StringVar(&flagRole, "role", "", "with -member-add, the member's role such as owner, developer, or viewer")
+	var flagCloneTo string
+	flag.StringVar(&flagCloneTo, "clone-to", "", "create a new project with this name containing the fleets, routes, and env vars of -project")
+	var flagSnapshot bool
+	flag.BoolVar(&flagSnapshot, "snapshot", false, "write the env vars, fleets, routes, and products of -project to the -out file")
+	var flagSnapshotRestore string
+	flag.StringVar(&flagSnapshotRestore, "snapshot-restore", "", "re-apply the configuration in this -snapshot file to -project (products only to the project it was taken from)")
+	var flagExportLocations string
+	flag.StringVar(&flagExportLocations, "export-locations", "", "export the last known location of devices in -scope (or all devices) as geojson or kml, to -out or stdout")
+	var flagDeviceWatch bool
+	flag.BoolVar(&flagDeviceWatch, "device-watch", false, "tail the events and health log of the single device in -scope")
+	var flagEventGet string
+	flag.StringVar(&flagEventGet, "event-get", "", "show the full detail of the event with the specified event UID")
+	var flagFleetDevices string
+	flag.StringVar(&flagFleetDevices, "fleet-devices", "", "list the devices in the specified fleet")
+	var flagCountOnly bool
+	flag.BoolVar(&flagCountOnly, "count-only", false, "with -fleet-devices, show only the number of devices")
+	var flagFleetEnv string
+	flag.StringVar(&flagFleetEnv, "fleet-env", "", "show the env vars of the specified fleet, after applying any -env-set and -env-delete")
+	var flagEnvSet multiFlag
+	flag.Var(&flagEnvSet, "env-set", "with -project-env, -fleet-env, or -device-env, set an env var as KEY=VALUE (may be repeated)")
+	var flagEnvDelete multiFlag
+	flag.Var(&flagEnvDelete, "env-delete", "with -project-env, -fleet-env, or -device-env, delete the env var KEY (may be repeated)")
+	var flagProjectEnv bool
+	flag.BoolVar(&flagProjectEnv, "project-env", false, "show the env vars of the project, after applying any -env-set and -env-delete")
+	var flagDeviceEnv bool
+	flag.BoolVar(&flagDeviceEnv, "device-env", false, "show the env vars of each device in -scope, after applying any -env-set and -env-delete")
+	var flagFleetWatchdog string
+	flag.StringVar(&flagFleetWatchdog, "fleet-watchdog", "", "list the devices in the specified fleet that have not been heard from within its watchdog window")
+	var flagEventsCount bool
+	flag.BoolVar(&flagEventsCount, "events-count", false, "show a histogram of event counts over time for the devices or fleets in -scope")
+	var flagEvents bool
+	flag.BoolVar(&flagEvents, "events", false, "show the recent events of the devices or fleets in -scope over the -since period (default 1d)")
+	var flagNotefile string
+	flag.StringVar(&flagNotefile, "notefile", "", "with -events, show only events in this notefile")
+	var flagLimit int
+	flag.IntVar(&flagLimit, "limit", 0, "with -events, show only this many of the most recent events")
+	var flagFirmwareSpread bool
+	flag.BoolVar(&flagFirmwareSpread, "firmware-spread", false, "show how many devices in -scope, or in the project, run each notecard and host firmware version")
+	var flagDfuStatus bool
+	flag.BoolVar(&flagDfuStatus, "dfu-status", false, "show the state of any firmware update of each device in -scope")
+	var flagDfuType string
+	flag.StringVar(&flagDfuType, "dfu-type", "", "with -dfu-status or -dfu-update, the type of firmware: notecard or host")
+	var flagDfuUpdate string
+	flag.StringVar(&flagDfuUpdate, "dfu-update", "", "schedule an update of the devices or fleets in -scope to the specified uploaded firmware file")
+	var flagForce bool
+	flag.BoolVar(&flagForce, "force", false, "with -dfu-update, schedule the update even if the firmware file isn't found in the project")
+	var flagBucket string
+	flag.StringVar(&flagBucket, "bucket", "", "width of each histogram bucket such as 15m, 1h, or 1d (default 1h)")
+	var flagSince string
+	flag.StringVar(&flagSince, "since", "", "how far back to look for events or route logs such as 12h or 7d")
 
 	// Parse these flags and also the note tool config flags
 	err := lib.FlagParse(false, true)
@@ -111,6 +262,20 @@ func main() {
 			os.Exit(exitFail)
 		}
 	}
+	if flagAutoReauth && !flagSignIn && flagSignInToken == "" {
+		if expiring, _ := lib.ConfigTokenExpiring(); expiring {
+			if token := lib.ConfigEnvToken(); token != "" {
+				fmt.Printf("notehub session is about to expire; signing in again with NOTEHUB_TOKEN\n")
+				err = authSignInToken(token)
+				if err != nil {
+					fmt.Printf("%s\n", err)
+					os.Exit(exitFail)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "warning: -auto-reauth requires a personal access token in NOTEHUB_TOKEN; continuing with the current session\n")
+			}
+		}
+	}
 	if flagSignOut {
 		err = authSignOut()
 		if err != nil {
@@ -119,6 +284,13 @@ func main() {
 		}
 	}
 
+	// Accept a project UID, product UID, or project name for -project
+	err = projectResolve(flagVerbose)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(exitFail)
+	}
+
 	// See if we did something
 	didSomething := false
 
@@ -135,6 +307,15 @@ func main() {
 		didSomething = true
 	}
 
+	if flagWhoAmI {
+		err = authWhoAmI(flagVerbose)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(exitFail)
+		}
+		didSomething = true
+	}
+
 	// Create an output function that will be used during -req processing
 	outq := make(chan string)
 	go func() {
@@ -212,6 +393,200 @@ func main() {
 		didSomething = true
 	}
 
+	// List fleets across all projects
+	if err == nil && flagAccountFleets {
+		err = accountFleets(flagJson, flagPretty, flagVerbose)
+		didSomething = true
+	}
+
+	// Manage the members of a project
+	if err == nil && (flagMembers || flagMemberAdd != "" || flagMemberRemove != "") {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = projectMembers(appMetadata, flagMemberAdd, flagMemberRemove, flagRole, flagYes, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Clone a project's configuration into a new project
+	if err == nil && flagCloneTo != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = projectClone(appMetadata, flagCloneTo, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Back up or restore a project's configuration
+	if err == nil && (flagSnapshot || flagSnapshotRestore != "") {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil && flagSnapshot {
+			err = projectSnapshot(appMetadata, flagOut, flagVerbose)
+		}
+		if err == nil && flagSnapshotRestore != "" {
+			err = projectRestore(appMetadata, flagSnapshotRestore, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Simulate delivery of an event through a route
+	if err == nil && flagRouteSimulate != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = routeSimulate(appMetadata, flagRouteSimulate, flagInput, flagLive, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Manage the custom HTTP headers of a route
+	if err == nil && flagRouteHeaders != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = routeHeaders(appMetadata, flagRouteHeaders, flagSetHeader, flagDeleteHeader, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Show a single device
+	if err == nil && flagDeviceGet != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = deviceGet(appMetadata, flagDeviceGet, flagPretty, flagJson, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// List devices in the project
+	if err == nil && flagListDevices {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = deviceList(appMetadata, flagListFilter, flagAll, flagPageSize, flagPageNum, flagJson, flagPretty, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Enable and move devices listed in a CSV, recording the outcome of each
+	if err == nil && flagBulkEnable != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = deviceBulkEnable(appMetadata, flagBulkEnable, flagOut, flagReason, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Create a route
+	if err == nil && flagRouteCreate != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = routeCreate(appMetadata, flagRouteCreate, flagConfig, flagRouteType, flagURL, flagThrottleMs, flagRouteTimeout, flagListFilter.Fleet, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Send a test event through a route
+	if err == nil && flagRouteTest != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = routeTest(appMetadata, flagRouteTest, flagBody, flagDevice, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Enable or disable a route
+	if err == nil && (flagRouteEnable != "" || flagRouteDisable != "") {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil && flagRouteEnable != "" {
+			err = routeEnable(appMetadata, flagRouteEnable, true, flagVerbose)
+		}
+		if err == nil && flagRouteDisable != "" {
+			err = routeEnable(appMetadata, flagRouteDisable, false, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Pause or resume all routing in the project
+	if err == nil && (flagRouteDisableAll || flagRouteEnableAll) {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil && flagRouteDisableAll && flagRouteEnableAll {
+			err = fmt.Errorf("-route-disable-all and -route-enable-all may not be combined")
+		} else if err == nil {
+			err = routeToggleAll(appMetadata, flagRouteDisableAll, flagRestore, flagYes, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// List the devices in a fleet
+	if err == nil && flagFleetDevices != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = fleetDevices(appMetadata, flagFleetDevices, flagCountOnly, flagJson, flagPretty, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Manage the env vars of the project
+	if err == nil && flagProjectEnv {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = projectEnv(appMetadata, flagEnvSet, flagEnvDelete, flagJson, flagPretty, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Manage the env vars of a fleet
+	if err == nil && flagFleetEnv != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = fleetEnv(appMetadata, flagFleetEnv, flagEnvSet, flagEnvDelete, flagJson, flagPretty, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Report devices that have breached their fleet's watchdog
+	if err == nil && flagFleetWatchdog != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = fleetWatchdog(appMetadata, flagFleetWatchdog, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Show a single event
+	if err == nil && flagEventGet != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = eventGet(appMetadata, flagEventGet, flagPretty, flagJson, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Show the delivery logs of a route
+	if err == nil && flagRouteLogs != "" {
+		var appMetadata AppMetadata
+		appMetadata, err = appGetMetadata(flagVerbose, false)
+		if err == nil {
+			err = routeLogs(appMetadata, flagRouteLogs, flagSince, flagAggregateErrors, flagVerbose)
+		}
+		didSomething = true
+	}
+
 	// Determine the scope of a later request
 	var scopeDevices, scopeFleets []string
 	var appMetadata AppMetadata
@@ -255,7 +630,9 @@ func main() {
 		} else if len(scopeFleets) != 0 {
 			vars, err = varsGetFromFleets(appMetadata, scopeFleets, flagVerbose)
 		}
-		if err == nil {
+		if err == nil && flagTable && !flagJson {
+			varsShowTable(vars)
+		} else if err == nil {
 			if flagPretty {
 				varsJSON, err = note.JSONMarshalIndent(vars, "", "    ")
 			} else {
@@ -300,6 +677,123 @@ func main() {
 		}
 	}
 
+	// Move devices between fleets
+	if err == nil && flagMoveToFleet != "" {
+		if len(scopeDevices) == 0 {
+			err = fmt.Errorf("use -scope to specify the device(s) to be moved")
+		} else {
+			err = fleetMoveDevices(appMetadata, scopeDevices, flagMoveToFleet, flagAdd, flagDryRun, flagVerbose)
+		}
+	}
+
+	// Enable or disable devices
+	if err == nil && (flagEnable || flagDisable) {
+		if flagEnable && flagDisable {
+			err = fmt.Errorf("-enable and -disable may not be combined")
+		} else if len(scopeDevices) == 0 {
+			err = fmt.Errorf("use -scope to specify the device(s) to be enabled or disabled")
+		} else {
+			err = deviceSetEnabled(appMetadata, scopeDevices, flagEnable, flagReason, flagDryRun, flagVerbose)
+		}
+	}
+
+	// Delete devices from the project
+	if err == nil && flagDelete {
+		if len(scopeDevices) == 0 {
+			err = fmt.Errorf("use -scope to specify the device(s) to be deleted")
+		} else {
+			err = deviceDelete(appMetadata, scopeDevices, flagYes, flagDryRun, flagVerbose)
+		}
+	}
+
+	// Remotely factory-reset devices
+	if err == nil && flagFactoryReset {
+		if len(scopeDevices) == 0 {
+			err = fmt.Errorf("use -scope to specify the device(s) to be factory reset")
+		} else {
+			err = deviceFactoryReset(appMetadata, scopeDevices, flagYes, flagReason, flagDryRun, flagVerbose)
+		}
+	}
+
+	// Export device locations for mapping
+	if err == nil && flagExportLocations != "" {
+		if flagScope == "" {
+			appMetadata, err = appGetMetadata(flagVerbose, false)
+		}
+		if err == nil {
+			err = locationsExport(appMetadata, scopeDevices, scopeFleets, flagExportLocations, flagOut, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Watch a single device
+	if err == nil && flagDeviceWatch {
+		if len(scopeDevices) != 1 {
+			err = fmt.Errorf("use -scope to specify the single device to watch")
+		} else {
+			err = deviceWatch(appMetadata, scopeDevices[0], flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Manage the env vars of devices
+	if err == nil && flagDeviceEnv {
+		if flagScope == "" {
+			err = fmt.Errorf("use -scope to specify the device(s) or fleet(s) whose env vars are to be managed")
+		} else {
+			err = deviceEnv(appMetadata, scopeDevices, scopeFleets, flagEnvSet, flagEnvDelete, flagJson, flagPretty, flagVerbose)
+		}
+	}
+
+	// Display the spread of firmware versions across devices
+	if err == nil && flagFirmwareSpread {
+		if flagScope == "" {
+			appMetadata, err = appGetMetadata(flagVerbose, false)
+		}
+		if err == nil {
+			err = deviceFirmwareSpread(appMetadata, scopeDevices, scopeFleets, flagJson, flagPretty, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Display the firmware update state of devices
+	if err == nil && flagDfuStatus {
+		if flagScope == "" {
+			err = fmt.Errorf("use -scope to specify the device(s) whose firmware update state is shown")
+		} else {
+			err = dfuStatus(appMetadata, scopeDevices, scopeFleets, flagDfuType, flagJson, flagPretty, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Schedule a firmware update of devices or fleets
+	if err == nil && flagDfuUpdate != "" {
+		if flagScope == "" {
+			err = fmt.Errorf("use -scope to specify the device(s) or fleet(s) to be updated")
+		} else {
+			err = dfuUpdate(appMetadata, scopeDevices, scopeFleets, flagDfuType, flagDfuUpdate, flagForce, flagDryRun, flagVerbose)
+		}
+		didSomething = true
+	}
+
+	// Display the recent events of devices or fleets
+	if err == nil && flagEvents {
+		if flagScope == "" {
+			err = fmt.Errorf("use -scope to specify the devices or fleets whose events should be shown")
+		} else {
+			err = eventsList(appMetadata, scopeDevices, scopeFleets, flagSince, flagNotefile, flagLimit, flagJson, flagPretty, flagVerbose)
+		}
+	}
+
+	// Display a histogram of event counts
+	if err == nil && flagEventsCount {
+		if flagScope == "" {
+			err = fmt.Errorf("use -scope to specify the devices or fleets whose events should be counted")
+		} else {
+			err = eventsCount(appMetadata, scopeDevices, scopeFleets, flagBucket, flagSince, flagJson, flagPretty, flagVerbose)
+		}
+	}
+
 	// Explore the contents of the device
 	if err == nil && len(scopeDevices) != 0 && flagExplore {
 		didSomething = true
@@ -0,0 +1,329 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// A project UID, with or without its "app:" prefix
+var projectUIDPattern = regexp.MustCompile(`^(app:)?[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// A product UID, either with its "product:" prefix or in reverse-domain form such as com.company.user:product
+var productUIDPattern = regexp.MustCompile(`^(product:.+|[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)+:[a-zA-Z0-9_.-]+)$`)
+
+// Fields of a route that are specific to one project and must not be copied
+var routeFieldsNotCopied = []string{"uid", "created", "modified"}
+
+// ProjectEnvVars is the request and response for project-level environment variables
+type ProjectEnvVars struct {
+	EnvironmentVariables Vars `json:"environment_variables"`
+}
+
+// Get a project-level resource list as generic JSON objects.  Some list endpoints return a bare
+// array, and others return an object with the array in a field named for the resource.
+func projectList(projectUID string, resource string, flagVerbose bool) (items []map[string]interface{}, err error) {
+	var rsp interface{}
+	url := fmt.Sprintf("/v1/projects/%s/%s", projectUID, resource)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &rsp)
+	if err != nil {
+		return
+	}
+	list, isList := rsp.([]interface{})
+	if !isList {
+		obj, _ := rsp.(map[string]interface{})
+		list, _ = obj[resource].([]interface{})
+	}
+	for _, v := range list {
+		item, ok := v.(map[string]interface{})
+		if ok {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// Resolve a -project that may be a project UID, a product UID, or a project name, so that
+// every command addresses the project in the same way.  A product UID is moved to flagProduct,
+// and a name is looked up among the projects accessible to this account.
+func projectResolve(flagVerbose bool) (err error) {
+
+	project := strings.TrimSpace(flagApp)
+	switch {
+
+	case project == "" || projectUIDPattern.MatchString(project):
+		return
+
+	case productUIDPattern.MatchString(project):
+		if flagProduct != "" && flagProduct != project {
+			return fmt.Errorf("-project %s is a product UID, which conflicts with -product %s", project, flagProduct)
+		}
+		flagProduct = project
+		flagApp = ""
+		return
+
+	}
+
+	rsp := AccountProjectsResponse{}
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", "/v1/projects", nil, &rsp)
+	if err != nil {
+		return
+	}
+	for _, p := range rsp.Projects {
+		if strings.EqualFold(p.Label, project) {
+			if flagApp != project {
+				return fmt.Errorf("more than one project is named '%s'; please use its project UID", project)
+			}
+			flagApp = p.UID
+		}
+	}
+	if flagApp == project {
+		return fmt.Errorf("'%s' is not a project UID, product UID, or the name of a project accessible to this account", project)
+	}
+
+	return
+
+}
+
+// Perform a V1 request with a generic JSON object as the body and the response
+func projectPost(verb string, url string, body interface{}, flagVerbose bool) (rsp map[string]interface{}, err error) {
+	var bodyJSON []byte
+	bodyJSON, err = note.JSONMarshal(body)
+	if err != nil {
+		return
+	}
+	rsp = map[string]interface{}{}
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), verb, url, bodyJSON, &rsp)
+	if err == nil {
+		rspErr, _ := rsp["err"].(string)
+		if rspErr != "" {
+			err = fmt.Errorf("%s", rspErr)
+		}
+	}
+	return
+}
+
+// Create a new project and copy the fleets, routes, and env vars of a source project into it.
+// Devices, fleet membership, and credentials are not copied.
+func projectClone(appMetadata AppMetadata, label string, flagVerbose bool) (err error) {
+
+	source := appMetadata.App.UID
+	copied := []string{}
+	skipped := []string{}
+
+	// Create the new project in the same billing account
+	newProject := map[string]interface{}{"label": label}
+	if appMetadata.App.BA != "" {
+		newProject["billing_account_uid"] = appMetadata.App.BA
+	}
+	var rsp map[string]interface{}
+	rsp, err = projectPost("POST", "/v1/projects", newProject, flagVerbose)
+	if err != nil {
+		return
+	}
+	target, _ := rsp["uid"].(string)
+	if target == "" {
+		return fmt.Errorf("project was not created")
+	}
+	fmt.Printf("created project %s (%s)\n", label, target)
+
+	// Project-level env vars
+	projectVars := ProjectEnvVars{}
+	url := fmt.Sprintf("/v1/projects/%s/environment_variables", source)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &projectVars)
+	if err == nil && len(projectVars.EnvironmentVariables) > 0 {
+		url = fmt.Sprintf("/v1/projects/%s/environment_variables", target)
+		_, err = projectPost("PUT", url, projectVars, flagVerbose)
+	}
+	if err != nil {
+		skipped = append(skipped, fmt.Sprintf("project env vars: %s", err))
+	} else {
+		copied = append(copied, fmt.Sprintf("%d project env vars", len(projectVars.EnvironmentVariables)))
+	}
+
+	// Fleets, with their smart rules and env vars, noting the new UID of each for use by routes
+	var fleets []map[string]interface{}
+	fleets, err = projectList(source, "fleets", flagVerbose)
+	if err != nil {
+		return
+	}
+	fleetUIDs := map[string]string{}
+	for _, fleet := range fleets {
+		fleetUID, _ := fleet["uid"].(string)
+		fleetLabel, _ := fleet["label"].(string)
+		newFleet := map[string]interface{}{"label": fleetLabel}
+		if rule, present := fleet["smart_rule"]; present {
+			newFleet["smart_rule"] = rule
+		}
+		url = fmt.Sprintf("/v1/projects/%s/fleets", target)
+		rsp, err = projectPost("POST", url, newFleet, flagVerbose)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("fleet '%s': %s", fleetLabel, err))
+			continue
+		}
+		newFleetUID, _ := rsp["uid"].(string)
+		fleetUIDs[fleetUID] = newFleetUID
+		var fleetVars map[string]Vars
+		fleetVars, err = varsGetFromFleets(appMetadata, []string{fleetUID}, flagVerbose)
+		if err == nil && len(fleetVars[fleetUID]) > 0 {
+			url = fmt.Sprintf("/v1/projects/%s/fleets/%s/environment_variables", target, newFleetUID)
+			_, err = projectPost("PUT", url, ProjectEnvVars{EnvironmentVariables: fleetVars[fleetUID]}, flagVerbose)
+		}
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("env vars of fleet '%s': %s", fleetLabel, err))
+			copied = append(copied, fmt.Sprintf("fleet '%s'", fleetLabel))
+		} else {
+			copied = append(copied, fmt.Sprintf("fleet '%s' with %d env vars", fleetLabel, len(fleetVars[fleetUID])))
+		}
+	}
+
+	// Routes, stripped of anything specific to the source project
+	var routes []map[string]interface{}
+	routes, err = projectList(source, "routes", flagVerbose)
+	if err != nil {
+		return
+	}
+	for _, r := range routes {
+		routeUID, _ := r["uid"].(string)
+		routeLabel, _ := r["label"].(string)
+		route := map[string]interface{}{}
+		url = fmt.Sprintf("/v1/projects/%s/routes/%s", source, routeUID)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &route)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("route '%s': %s", routeLabel, err))
+			continue
+		}
+		for _, field := range routeFieldsNotCopied {
+			delete(route, field)
+		}
+		if unmapped := routeMapFleets(route, fleetUIDs); len(unmapped) > 0 {
+			skipped = append(skipped, fmt.Sprintf("fleets %s of route '%s': not copied to the new project, so removed from its fleet filter", strings.Join(unmapped, ", "), routeLabel))
+		}
+		url = fmt.Sprintf("/v1/projects/%s/routes", target)
+		_, err = projectPost("POST", url, route, flagVerbose)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("route '%s': %s", routeLabel, err))
+			continue
+		}
+		copied = append(copied, fmt.Sprintf("route '%s'", routeLabel))
+	}
+	err = nil
+
+	// Report the results
+	for _, s := range copied {
+		fmt.Printf("copied %s\n", s)
+	}
+	for _, s := range skipped {
+		fmt.Printf("SKIPPED %s\n", s)
+	}
+	fmt.Printf("devices, fleet membership, and credentials were not copied\n")
+
+	return
+
+}
+
+// ProjectMember is a user with access to a project
+type ProjectMember struct {
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+// ProjectMembersResponse is the list of a project's members
+type ProjectMembersResponse struct {
+	Members []ProjectMember `json:"members,omitempty"`
+}
+
+// List, add, or remove the members of a project
+func projectMembers(appMetadata AppMetadata, add string, remove string, role string, confirmed bool, flagVerbose bool) (err error) {
+
+	url := fmt.Sprintf("/v1/projects/%s/members", appMetadata.App.UID)
+
+	if add != "" {
+		if role == "" {
+			return fmt.Errorf("use -role to specify the role of the member being added")
+		}
+		_, err = projectPost("POST", url, ProjectMember{Email: add, Role: role}, flagVerbose)
+		if err != nil {
+			return
+		}
+		fmt.Printf("added %s as %s\n", add, role)
+	}
+
+	if remove != "" {
+		if !confirmed {
+			return fmt.Errorf("this will remove %s's access to %s; use -yes to confirm", remove, appMetadata.App.Name)
+		}
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "DELETE", url+"/"+remove, nil, nil)
+		if err != nil {
+			return
+		}
+		fmt.Printf("removed %s\n", remove)
+	}
+
+	if add != "" || remove != "" {
+		return
+	}
+
+	rsp := ProjectMembersResponse{}
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &rsp)
+	if err != nil {
+		return
+	}
+	for _, m := range rsp.Members {
+		fmt.Printf("%-40s %-12s %s\n", m.Email, m.Role, m.Name)
+	}
+	fmt.Printf("%d members of %s\n", len(rsp.Members), appMetadata.App.Name)
+
+	return
+
+}
+
+// Show the project's env vars after setting and deleting any that were specified.  Values
+// being set are merged with the existing vars so that unrelated vars are preserved.
+func projectEnv(appMetadata AppMetadata, set []string, remove []string, flagJson bool, flagPretty bool, flagVerbose bool) (err error) {
+
+	url := fmt.Sprintf("/v1/projects/%s/environment_variables", appMetadata.App.UID)
+	projectVars := ProjectEnvVars{}
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &projectVars)
+	if err != nil {
+		return
+	}
+	if projectVars.EnvironmentVariables == nil {
+		projectVars.EnvironmentVariables = Vars{}
+	}
+
+	if len(set) > 0 {
+		var template Vars
+		template, err = varsParseAssignments(set)
+		if err != nil {
+			return
+		}
+		for k, v := range template {
+			projectVars.EnvironmentVariables[k] = v
+		}
+		_, err = projectPost("PUT", url, projectVars, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, key := range remove {
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "DELETE", url+"/"+neturl.PathEscape(key), nil, nil)
+		if err != nil {
+			return fmt.Errorf("can't delete %s: %s", key, err)
+		}
+		delete(projectVars.EnvironmentVariables, key)
+	}
+
+	return varsShow(map[string]Vars{appMetadata.App.Name: projectVars.EnvironmentVariables}, flagJson, flagPretty)
+
+}
@@ -6,11 +6,14 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"runtime"
 	"strings"
 
 	"github.com/blues/note-cli/lib"
@@ -35,6 +38,29 @@ func addQuery(in string, key string, value string) (out string) {
 	return
 }
 
+// The User-Agent sent with all HTTP requests, identifying the CLI version and OS
+func httpUserAgent() string {
+	return fmt.Sprintf("notehub-client/%s (%s; %s)", version, runtime.GOOS, runtime.GOARCH)
+}
+
+// Generate the request ID for an HTTP request, unless one was specified on the command line
+func httpRequestID() string {
+	if flagRequestID != "" {
+		return flagRequestID
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Set the standard headers for all HTTP requests, returning the request ID
+func httpSetHeaders(httpReq *http.Request) (requestID string) {
+	requestID = httpRequestID()
+	httpReq.Header.Set("User-Agent", httpUserAgent())
+	httpReq.Header.Set("X-Request-ID", requestID)
+	return
+}
+
 // Perform a hub transaction, and promote the returned err response to an error to this method
 func hubTransactionRequest(request notehub.HubRequest, verbose bool) (rsp notehub.HubRequest, err error) {
 	var reqJSON []byte
@@ -115,7 +141,7 @@ func reqHubV0JSON(verbose bool, hub string, request []byte, requestFile string,
 	if err != nil {
 		return
 	}
-	httpReq.Header.Set("User-Agent", "notehub-client")
+	requestID := httpSetHeaders(httpReq)
 	if requestFile != "" {
 		httpReq.Header.Set("Content-Length", fmt.Sprintf("%d", fileLength))
 		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -129,6 +155,7 @@ func reqHubV0JSON(verbose bool, hub string, request []byte, requestFile string,
 	}
 
 	if verbose {
+		fmt.Printf("X-Request-ID: %s\n", requestID)
 		fmt.Printf("%s\n", string(request))
 	}
 
@@ -225,7 +252,7 @@ func reqHubV1JSON(verbose bool, hub string, verb string, url string, body []byte
 	if err != nil {
 		return
 	}
-	httpReq.Header.Set("User-Agent", "notehub-client")
+	requestID := httpSetHeaders(httpReq)
 	httpReq.Header.Set("Content-Type", "application/json")
 	err = lib.ConfigAuthenticationHeader(httpReq)
 	if err != nil {
@@ -234,6 +261,7 @@ func reqHubV1JSON(verbose bool, hub string, verb string, url string, body []byte
 
 	if verbose {
 		fmt.Printf("%s %s\n", verb, httpurl)
+		fmt.Printf("X-Request-ID: %s\n", requestID)
 		if len(body) != 0 {
 			fmt.Printf("%s\n", string(body))
 		}
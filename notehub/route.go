@@ -0,0 +1,664 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+	"github.com/blues/note-go/notehub"
+)
+
+// RouteHTTP is the configuration of an HTTP route's target
+type RouteHTTP struct {
+	URL         string            `json:"url,omitempty"`
+	HTTPHeaders map[string]string `json:"http_headers,omitempty"`
+	Timeout     int               `json:"timeout,omitempty"`
+}
+
+// Route is the subset of a notehub route's configuration that we make use of
+type Route struct {
+	UID      string     `json:"uid,omitempty"`
+	Label    string     `json:"label,omitempty"`
+	Type     string     `json:"type,omitempty"`
+	Disabled bool       `json:"disabled,omitempty"`
+	HTTP     *RouteHTTP `json:"http,omitempty"`
+}
+
+// Find a route within the app by UID or by name
+func routeFind(appMetadata AppMetadata, route string) (found Metadata, err error) {
+	for _, r := range appMetadata.Routes {
+		if r.UID == route || strings.EqualFold(r.Name, route) {
+			return r, nil
+		}
+	}
+	err = fmt.Errorf("route '%s' not found in project", route)
+	return
+}
+
+// Get the configuration of a route
+func routeGet(appMetadata AppMetadata, routeUID string, flagVerbose bool) (route Route, err error) {
+	url := fmt.Sprintf("/v1/projects/%s/routes/%s", appMetadata.App.UID, routeUID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &route)
+	return
+}
+
+// Perform a route's outbound delivery of an event from this host directly to the route's target
+func routeSimulate(appMetadata AppMetadata, routeName string, inputFile string, live bool, flagVerbose bool) (err error) {
+
+	// Load the event to be delivered
+	if inputFile == "" {
+		return fmt.Errorf("use -input to specify a .json file containing the event to deliver")
+	}
+	var eventJSON []byte
+	eventJSON, err = ioutil.ReadFile(inputFile)
+	if err != nil {
+		return
+	}
+	var event map[string]interface{}
+	err = note.JSONUnmarshal(eventJSON, &event)
+	if err != nil {
+		return fmt.Errorf("%s: %s", inputFile, err)
+	}
+
+	return routeDeliver(appMetadata, routeName, eventJSON, live, flagVerbose)
+
+}
+
+// Deliver an event from this host directly to the target of an HTTP route, or just show
+// what would be delivered unless live
+func routeDeliver(appMetadata AppMetadata, routeName string, eventJSON []byte, live bool, flagVerbose bool) (err error) {
+
+	// Load the route's target configuration
+	var r Metadata
+	r, err = routeFind(appMetadata, routeName)
+	if err != nil {
+		return
+	}
+	var route Route
+	route, err = routeGet(appMetadata, r.UID, flagVerbose)
+	if err != nil {
+		return
+	}
+	if route.HTTP == nil || route.HTTP.URL == "" {
+		return fmt.Errorf("route '%s' is of type '%s', and only HTTP routes may be simulated", r.Name, route.Type)
+	}
+
+	// Show what will be sent
+	fmt.Printf("POST %s\n", route.HTTP.URL)
+	for k, v := range route.HTTP.HTTPHeaders {
+		fmt.Printf("%s: %s\n", k, v)
+	}
+	fmt.Printf("%s\n", eventJSON)
+	if !live {
+		fmt.Printf("use -live to actually deliver this event to the route's target\n")
+		return
+	}
+
+	// Deliver it
+	httpReq, err := http.NewRequest("POST", route.HTTP.URL, bytes.NewBuffer(eventJSON))
+	if err != nil {
+		return
+	}
+	httpSetHeaders(httpReq)
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range route.HTTP.HTTPHeaders {
+		httpReq.Header.Set(k, v)
+	}
+	timeout := 15
+	if route.HTTP.Timeout > 0 {
+		timeout = route.HTTP.Timeout
+	}
+	httpClient := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	began := time.Now()
+	httpRsp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return
+	}
+	defer httpRsp.Body.Close()
+	rspBody, err := ioutil.ReadAll(httpRsp.Body)
+	if err != nil {
+		return
+	}
+
+	// Report the target's response
+	fmt.Printf("STATUS %d (%d ms)\n", httpRsp.StatusCode, time.Since(began).Milliseconds())
+	if len(rspBody) != 0 {
+		fmt.Printf("%s\n", rspBody)
+	}
+	if httpRsp.StatusCode < 200 || httpRsp.StatusCode >= 300 {
+		err = fmt.Errorf("route target rejected the event with status %d", httpRsp.StatusCode)
+	}
+
+	return
+
+}
+
+// RouteLog is a single attempt by notehub to deliver an event through a route
+type RouteLog struct {
+	Date     string `json:"date,omitempty"`
+	EventUID string `json:"event_uid,omitempty"`
+	Attn     bool   `json:"attn,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Text     string `json:"text,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// RouteLogError is a distinct error returned by a route target, and how often it occurred
+type RouteLogError struct {
+	Message string
+	Count   int
+	First   string
+	Last    string
+}
+
+// Runs of digits and hex identifiers vary between otherwise-identical errors
+var routeLogVarying = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F-]{27}|[0-9]+`)
+
+// True if the delivery attempt failed
+func (l RouteLog) failed() bool {
+	return l.Attn || (l.Status != "" && !strings.HasPrefix(l.Status, "2"))
+}
+
+// Get the delivery logs of a route, newest first, back to the specified time
+func routeLogsGet(appMetadata AppMetadata, routeUID string, since time.Time, flagVerbose bool) (logs []RouteLog, err error) {
+	url := fmt.Sprintf("/v1/projects/%s/routes/%s/route-logs?sortOrder=desc", appMetadata.App.UID, routeUID)
+	err = paginate(url, 100, flagVerbose, func(pageJSON []byte) error {
+		page := []RouteLog{}
+		err := note.JSONUnmarshal(pageJSON, &page)
+		if err != nil {
+			return err
+		}
+		for _, l := range page {
+			t, err2 := time.Parse(time.RFC3339, l.Date)
+			if err2 == nil && t.Before(since) {
+				return errPaginateStop
+			}
+			logs = append(logs, l)
+		}
+		return nil
+	})
+	return
+}
+
+// Display the delivery logs of a route, optionally grouping the failures by error message
+func routeLogs(appMetadata AppMetadata, routeName string, sinceDuration string, aggregate bool, flagVerbose bool) (err error) {
+
+	since := time.Now().UTC().Add(-24 * time.Hour)
+	if sinceDuration != "" {
+		var d time.Duration
+		d, err = parseDuration(sinceDuration)
+		if err != nil {
+			return
+		}
+		since = time.Now().UTC().Add(-d)
+	}
+
+	var r Metadata
+	r, err = routeFind(appMetadata, routeName)
+	if err != nil {
+		return
+	}
+	var logs []RouteLog
+	logs, err = routeLogsGet(appMetadata, r.UID, since, flagVerbose)
+	if err != nil {
+		return
+	}
+
+	// List every entry
+	if !aggregate {
+		for _, l := range logs {
+			fmt.Printf("%s %-4s %s %s\n", l.Date, l.Status, l.EventUID, strings.TrimSpace(l.Text))
+		}
+		fmt.Printf("%d deliveries since %s\n", len(logs), since.Format(time.RFC3339))
+		return
+	}
+
+	// Group the failures by normalized message.  Logs are newest first, so the
+	// first one seen for an error is its most recent occurrence.
+	distinct := map[string]*RouteLogError{}
+	failures := 0
+	for _, l := range logs {
+		if !l.failed() {
+			continue
+		}
+		failures++
+		message := strings.TrimSpace(l.Status + " " + routeLogVarying.ReplaceAllString(strings.TrimSpace(l.Text), "#"))
+		e, present := distinct[message]
+		if !present {
+			e = &RouteLogError{Message: message, Last: l.Date}
+			distinct[message] = e
+		}
+		e.Count++
+		e.First = l.Date
+	}
+	sorted := []*RouteLogError{}
+	for _, e := range distinct {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Message < sorted[j].Message
+	})
+
+	fmt.Printf("%d of %d deliveries failed since %s, with %d distinct errors\n", failures, len(logs), since.Format(time.RFC3339), len(sorted))
+	for _, e := range sorted {
+		fmt.Printf("\n%6d x %s\n", e.Count, e.Message)
+		fmt.Printf("         first %s, last %s\n", e.First, e.Last)
+	}
+
+	return
+
+}
+
+// Show, set, or delete the custom HTTP headers of a route, leaving all of its other configuration as-is
+func routeHeaders(appMetadata AppMetadata, routeName string, set []string, remove []string, flagVerbose bool) (err error) {
+
+	var r Metadata
+	r, err = routeFind(appMetadata, routeName)
+	if err != nil {
+		return
+	}
+	route := map[string]interface{}{}
+	url := fmt.Sprintf("/v1/projects/%s/routes/%s", appMetadata.App.UID, r.UID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &route)
+	if err != nil {
+		return
+	}
+	httpConfig, isHTTP := route["http"].(map[string]interface{})
+	if !isHTTP {
+		routeType, _ := route["type"].(string)
+		return fmt.Errorf("route '%s' is of type '%s', and only HTTP routes have headers", r.Name, routeType)
+	}
+	headers, _ := httpConfig["http_headers"].(map[string]interface{})
+	if headers == nil {
+		headers = map[string]interface{}{}
+	}
+
+	// Modify the headers if requested
+	if len(set) != 0 || len(remove) != 0 {
+		for _, kv := range set {
+			i := strings.Index(kv, ":")
+			if i <= 0 {
+				return fmt.Errorf("header '%s' must be in the form KEY:VALUE", kv)
+			}
+			headers[strings.TrimSpace(kv[:i])] = strings.TrimSpace(kv[i+1:])
+		}
+		for _, k := range remove {
+			if _, present := headers[k]; !present {
+				return fmt.Errorf("route '%s' has no header '%s'", r.Name, k)
+			}
+			delete(headers, k)
+		}
+		httpConfig["http_headers"] = headers
+		_, err = projectPost("PUT", url, map[string]interface{}{"http": httpConfig}, flagVerbose)
+		if err != nil {
+			return
+		}
+	}
+
+	// Show the headers
+	keys := []string{}
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%s: %v\n", k, headers[k])
+	}
+	if len(keys) == 0 {
+		fmt.Printf("route '%s' has no custom headers\n", r.Name)
+	}
+
+	return
+
+}
+
+// File recording which routes were enabled before a project-wide disable, so that they alone
+// may later be restored
+func routeDisabledPath(projectUID string) string {
+	return lib.ConfigDir() + "/routes-disabled-" + projectUID + ".json"
+}
+
+// Disable or enable every route in the project.  When disabling, the routes that had been
+// enabled are recorded so that a later enable with restore re-enables only those.
+func routeToggleAll(appMetadata AppMetadata, disable bool, restore bool, confirmed bool, flagVerbose bool) (err error) {
+
+	verb := "enable"
+	if disable {
+		verb = "disable"
+	}
+	if !confirmed {
+		return fmt.Errorf("this will %s all %d routes in the project; use -yes to confirm", verb, len(appMetadata.Routes))
+	}
+
+	// Determine which routes to act upon when restoring
+	statePath := routeDisabledPath(appMetadata.App.UID)
+	var restoreUIDs []string
+	if !disable && restore {
+		var contents []byte
+		contents, err = ioutil.ReadFile(statePath)
+		if err != nil {
+			return fmt.Errorf("no record of routes disabled in this project: %s", err)
+		}
+		err = note.JSONUnmarshal(contents, &restoreUIDs)
+		if err != nil {
+			return
+		}
+	}
+
+	changed := 0
+	failed := 0
+	wasEnabled := []string{}
+	for _, r := range appMetadata.Routes {
+
+		if restoreUIDs != nil {
+			found := false
+			for _, uid := range restoreUIDs {
+				if uid == r.UID {
+					found = true
+				}
+			}
+			if !found {
+				fmt.Printf("%-40s skipped (was not enabled before disable)\n", r.Name)
+				continue
+			}
+		}
+
+		var route Route
+		route, err = routeGet(appMetadata, r.UID, flagVerbose)
+		if err != nil {
+			failed++
+			fmt.Printf("%-40s FAILED: %s\n", r.Name, err)
+			continue
+		}
+		if !route.Disabled {
+			wasEnabled = append(wasEnabled, r.UID)
+		}
+		if route.Disabled == disable {
+			fmt.Printf("%-40s already %sd\n", r.Name, verb)
+			continue
+		}
+
+		err = routeSetDisabled(appMetadata, r.UID, disable, flagVerbose)
+		if err != nil {
+			failed++
+			fmt.Printf("%-40s FAILED: %s\n", r.Name, err)
+			continue
+		}
+		changed++
+		fmt.Printf("%-40s %sd\n", r.Name, verb)
+
+	}
+	err = nil
+
+	// Remember what was running so that it alone can be restored, or forget it once restored.
+	// A prior record is merged rather than replaced, because a repeated disable finds the
+	// routes that it recorded already disabled.
+	if disable {
+		if contents, err2 := ioutil.ReadFile(statePath); err2 == nil {
+			var recorded []string
+			if note.JSONUnmarshal(contents, &recorded) == nil && len(recorded) > 0 {
+				wasEnabled = sortAndRemoveDuplicates(append(recorded, wasEnabled...))
+			}
+		}
+		var contents []byte
+		contents, err = note.JSONMarshal(wasEnabled)
+		if err == nil {
+			err = ioutil.WriteFile(statePath, contents, 0644)
+		}
+		if err != nil {
+			return fmt.Errorf("routes were disabled but their prior state could not be recorded: %s", err)
+		}
+	} else if restore && failed == 0 {
+		os.Remove(statePath)
+	}
+
+	fmt.Printf("%d routes %sd, %d failed, %d unchanged\n", changed, verb, failed, len(appMetadata.Routes)-changed-failed)
+	if failed > 0 {
+		err = fmt.Errorf("%d routes could not be %sd", failed, verb)
+	}
+
+	return
+
+}
+
+// Rewrite the fleets by which a route's target is filtered, stored as <type>.fleets, through
+// a mapping such as from one project's fleet UIDs to another's, returning those that have no
+// mapping.  Fleets with no mapping are removed from the filter.
+func routeMapFleets(route map[string]interface{}, mapping map[string]string) (unmapped []string) {
+	routeType, _ := route["type"].(string)
+	target, _ := route[routeType].(map[string]interface{})
+	fleets, _ := target["fleets"].([]interface{})
+	if len(fleets) == 0 {
+		return
+	}
+	mapped := []string{}
+	for _, f := range fleets {
+		from := fmt.Sprint(f)
+		to, present := mapping[from]
+		if !present {
+			unmapped = append(unmapped, from)
+			continue
+		}
+		mapped = append(mapped, to)
+	}
+	target["fleets"] = mapped
+	return
+}
+
+// Create a route, starting from a JSON configuration file if specified and overriding its
+// label, type, and the target's url, throttle, timeout, and fleets with any that are given
+func routeCreate(appMetadata AppMetadata, label string, configFile string, routeType string, targetURL string, throttleMs int, timeoutSecs int, fleet string, flagVerbose bool) (err error) {
+
+	route := map[string]interface{}{}
+	if configFile != "" {
+		var contents []byte
+		contents, err = ioutil.ReadFile(configFile)
+		if err != nil {
+			return
+		}
+		err = note.JSONUnmarshal(contents, &route)
+		if err != nil {
+			return fmt.Errorf("%s: %s", configFile, err)
+		}
+	}
+
+	route["label"] = label
+	if routeType != "" {
+		route["type"] = routeType
+	}
+	routeType, _ = route["type"].(string)
+	if routeType == "" {
+		return fmt.Errorf("use -route-type or a -config file to specify the type of route, such as http")
+	}
+
+	// The target's configuration is in a field named for the route type
+	target, _ := route[routeType].(map[string]interface{})
+	if target == nil {
+		target = map[string]interface{}{}
+	}
+	if targetURL != "" {
+		target["url"] = targetURL
+	}
+	if throttleMs != 0 {
+		target["throttle_ms"] = throttleMs
+	}
+	if timeoutSecs != 0 {
+		target["timeout"] = timeoutSecs
+	}
+	if fleet != "" {
+		var f Metadata
+		f, err = fleetFind(appMetadata, fleet)
+		if err != nil {
+			return
+		}
+		target["fleets"] = []string{f.UID}
+	}
+	route[routeType] = target
+
+	url := fmt.Sprintf("/v1/projects/%s/routes", appMetadata.App.UID)
+	var rsp map[string]interface{}
+	rsp, err = projectPost("POST", url, route, flagVerbose)
+	if err != nil {
+		return
+	}
+	routeUID, _ := rsp["uid"].(string)
+	fmt.Printf("created %s route '%s' (%s)\n", routeType, label, routeUID)
+
+	return
+
+}
+
+// Set whether a route is disabled, preserving the rest of its configuration by updating
+// the route as most recently fetched rather than sending only the changed field
+func routeSetDisabled(appMetadata AppMetadata, routeUID string, disabled bool, flagVerbose bool) (err error) {
+	route := map[string]interface{}{}
+	url := fmt.Sprintf("/v1/projects/%s/routes/%s", appMetadata.App.UID, routeUID)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &route)
+	if err != nil {
+		return
+	}
+	route["disabled"] = disabled
+	_, err = projectPost("PUT", url, route, flagVerbose)
+	return
+}
+
+// Enable or disable a single route
+func routeEnable(appMetadata AppMetadata, routeName string, enable bool, flagVerbose bool) (err error) {
+
+	var r Metadata
+	r, err = routeFind(appMetadata, routeName)
+	if err != nil {
+		return
+	}
+	err = routeSetDisabled(appMetadata, r.UID, !enable, flagVerbose)
+	if err != nil {
+		return
+	}
+
+	// Report the status as notehub now has it
+	var route Route
+	route, err = routeGet(appMetadata, r.UID, flagVerbose)
+	if err != nil {
+		return
+	}
+	status := "enabled"
+	if route.Disabled {
+		status = "disabled"
+	}
+	fmt.Printf("route '%s' is %s\n", r.Name, status)
+	if route.Disabled == enable {
+		err = fmt.Errorf("route '%s' did not change state", r.Name)
+	}
+
+	return
+
+}
+
+// Notefile into which test events are added, so that routes may filter them out
+const routeTestNotefile = "_test.qo"
+
+// How long to wait for a test event to appear in a route's delivery logs
+const routeTestWaitSecs = 60
+
+// Add a test event to a device's outbound notefile through the hub, so that it passes through
+// the route's filters, transforms, and delivery just as a real event would, and report the
+// route's delivery log entry for it
+func routeTest(appMetadata AppMetadata, routeName string, bodyJSON string, device string, flagVerbose bool) (err error) {
+
+	if device == "" {
+		return fmt.Errorf("use -device to specify the device from which the test event is sent")
+	}
+	body := map[string]interface{}{"test": true}
+	if bodyJSON != "" {
+		body = map[string]interface{}{}
+		err = note.JSONUnmarshal([]byte(bodyJSON), &body)
+		if err != nil {
+			return fmt.Errorf("-body must be a JSON object: %s", err)
+		}
+	}
+
+	var r Metadata
+	r, err = routeFind(appMetadata, routeName)
+	if err != nil {
+		return
+	}
+	var deviceUID string
+	deviceUID, err = deviceResolve(appMetadata, device, flagVerbose)
+	if err != nil {
+		return
+	}
+
+	// Hub requests are addressed to the device in flagDevice
+	saveDevice := flagDevice
+	defer func() { flagDevice = saveDevice }()
+	flagDevice = deviceUID
+
+	began := time.Now().UTC().Add(-1 * time.Second)
+	req := notehub.HubRequest{}
+	req.Req = "note.add"
+	req.NotefileID = routeTestNotefile
+	req.Body = &body
+	_, err = hubTransactionRequest(req, flagVerbose)
+	if err != nil {
+		return
+	}
+	fmt.Printf("test event added to %s of %s; waiting for route %s to deliver it\n", routeTestNotefile, deviceUID, r.Name)
+
+	// Wait for the event to be received and for its delivery to be logged, reporting the
+	// most recent attempt to deliver that specific event
+	eventUID := ""
+	for time.Since(began).Seconds() < routeTestWaitSecs {
+		time.Sleep(3 * time.Second)
+		if eventUID == "" {
+			var events []Event
+			events, err = eventsGet(appMetadata, []string{deviceUID}, nil, began, flagVerbose)
+			if err != nil {
+				return
+			}
+			for _, e := range events {
+				if e.NotefileID == routeTestNotefile {
+					eventUID = e.EventUID
+				}
+			}
+			if eventUID == "" {
+				continue
+			}
+		}
+		var logs []RouteLog
+		logs, err = routeLogsGet(appMetadata, r.UID, began, flagVerbose)
+		if err != nil {
+			return
+		}
+		for _, l := range logs {
+			if l.EventUID != eventUID {
+				continue
+			}
+			fmt.Printf("%s %-4s %s %s\n", l.Date, l.Status, l.EventUID, strings.TrimSpace(l.Text))
+			if l.failed() {
+				err = fmt.Errorf("route %s failed to deliver the test event", r.Name)
+			}
+			return
+		}
+	}
+
+	if eventUID == "" {
+		return fmt.Errorf("test event was not received by notehub within %d seconds", routeTestWaitSecs)
+	}
+	return fmt.Errorf("route %s logged no delivery of event %s within %d seconds; its filters may exclude %s", r.Name, eventUID, routeTestWaitSecs, routeTestNotefile)
+
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+)
+
+// ProjectSnapshotFleet is a fleet's configuration within a project snapshot
+type ProjectSnapshotFleet struct {
+	Label        string      `json:"label,omitempty"`
+	SmartRule    interface{} `json:"smart_rule,omitempty"`
+	WatchdogMins int64       `json:"watchdog_mins,omitempty"`
+	EnvVars      Vars        `json:"environment_variables,omitempty"`
+}
+
+// ProjectSnapshot is the complete configuration of a project, for backup and restore
+type ProjectSnapshot struct {
+	ProjectUID string                   `json:"project,omitempty"`
+	Label      string                   `json:"label,omitempty"`
+	Taken      string                   `json:"taken,omitempty"`
+	EnvVars    Vars                     `json:"environment_variables,omitempty"`
+	Fleets     []ProjectSnapshotFleet   `json:"fleets,omitempty"`
+	Routes     []map[string]interface{} `json:"routes,omitempty"`
+	Products   []map[string]interface{} `json:"products,omitempty"`
+}
+
+// Capture the configuration of a project's env vars, fleets, routes, and products into a file
+func projectSnapshot(appMetadata AppMetadata, outfile string, flagVerbose bool) (err error) {
+
+	if outfile == "" {
+		return fmt.Errorf("use -out to specify the file into which the snapshot is written")
+	}
+
+	project := appMetadata.App.UID
+	snapshot := ProjectSnapshot{}
+	snapshot.ProjectUID = project
+	snapshot.Label = appMetadata.App.Name
+	snapshot.Taken = time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	// Project-level env vars
+	projectVars := ProjectEnvVars{}
+	url := fmt.Sprintf("/v1/projects/%s/environment_variables", project)
+	err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &projectVars)
+	if err != nil {
+		return
+	}
+	snapshot.EnvVars = projectVars.EnvironmentVariables
+
+	// Fleets, with their smart rules, watchdogs, and env vars, noting the label of each so that
+	// routes refer to fleets by a name that is meaningful in whichever project is restored
+	fleetLabels := map[string]string{}
+	var fleets []map[string]interface{}
+	fleets, err = projectList(project, "fleets", flagVerbose)
+	if err != nil {
+		return
+	}
+	for _, fleet := range fleets {
+		fleetUID, _ := fleet["uid"].(string)
+		f := ProjectSnapshotFleet{}
+		f.Label, _ = fleet["label"].(string)
+		fleetLabels[fleetUID] = f.Label
+		f.SmartRule = fleet["smart_rule"]
+		watchdog := FleetWatchdog{}
+		url = fmt.Sprintf("/v1/projects/%s/fleets/%s", project, fleetUID)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &watchdog)
+		if err != nil {
+			return
+		}
+		f.WatchdogMins = watchdog.WatchdogMins
+		var fleetVars map[string]Vars
+		fleetVars, err = varsGetFromFleets(appMetadata, []string{fleetUID}, flagVerbose)
+		if err != nil {
+			return
+		}
+		f.EnvVars = fleetVars[fleetUID]
+		snapshot.Fleets = append(snapshot.Fleets, f)
+	}
+
+	// Routes, stripped of anything specific to this project and with fleets given by label
+	var routes []map[string]interface{}
+	routes, err = projectList(project, "routes", flagVerbose)
+	if err != nil {
+		return
+	}
+	for _, r := range routes {
+		routeUID, _ := r["uid"].(string)
+		route := map[string]interface{}{}
+		url = fmt.Sprintf("/v1/projects/%s/routes/%s", project, routeUID)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &route)
+		if err != nil {
+			return
+		}
+		for _, field := range routeFieldsNotCopied {
+			delete(route, field)
+		}
+		if unmapped := routeMapFleets(route, fleetLabels); len(unmapped) > 0 {
+			label, _ := route["label"].(string)
+			fmt.Printf("route '%s': fleets %s no longer exist, so were removed from its fleet filter\n", label, strings.Join(unmapped, ", "))
+		}
+		snapshot.Routes = append(snapshot.Routes, route)
+	}
+
+	// Products
+	snapshot.Products, err = projectList(project, "products", flagVerbose)
+	if err != nil {
+		return
+	}
+
+	var snapshotJSON []byte
+	snapshotJSON, err = note.JSONMarshalIndent(snapshot, "", "    ")
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(outfile, snapshotJSON, 0644)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("project %s: %d project env vars, %d fleets, %d routes, %d products written to %s\n",
+		project, len(snapshot.EnvVars), len(snapshot.Fleets), len(snapshot.Routes), len(snapshot.Products), outfile)
+	return
+
+}
+
+// Re-apply a project snapshot to the project, creating fleets, routes, and products that
+// don't already exist by name and updating the configuration of those that do.  Because
+// product UIDs are unique across all of Notehub, products are only restored into the
+// project from which the snapshot was taken.
+func projectRestore(appMetadata AppMetadata, infile string, flagVerbose bool) (err error) {
+
+	contents, err := ioutil.ReadFile(infile)
+	if err != nil {
+		return
+	}
+	snapshot := ProjectSnapshot{}
+	err = note.JSONUnmarshal(contents, &snapshot)
+	if err != nil {
+		return fmt.Errorf("%s is not a project snapshot: %s", infile, err)
+	}
+
+	project := appMetadata.App.UID
+	restored := []string{}
+	skipped := []string{}
+
+	// Project-level env vars
+	if len(snapshot.EnvVars) > 0 {
+		url := fmt.Sprintf("/v1/projects/%s/environment_variables", project)
+		_, err = projectPost("PUT", url, ProjectEnvVars{EnvironmentVariables: snapshot.EnvVars}, flagVerbose)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("project env vars: %s", err))
+		} else {
+			restored = append(restored, fmt.Sprintf("%d project env vars", len(snapshot.EnvVars)))
+		}
+	}
+
+	// Fleets, matched to existing fleets by name, noting the UID of each for use by routes
+	fleetUIDs := map[string]string{}
+	for _, f := range snapshot.Fleets {
+		fleet := map[string]interface{}{"label": f.Label}
+		if f.SmartRule != nil {
+			fleet["smart_rule"] = f.SmartRule
+		}
+		if f.WatchdogMins != 0 {
+			fleet["watchdog_mins"] = f.WatchdogMins
+		}
+		fleetUID := ""
+		existing, err2 := fleetFind(appMetadata, f.Label)
+		if err2 == nil {
+			fleetUID = existing.UID
+			url := fmt.Sprintf("/v1/projects/%s/fleets/%s", project, fleetUID)
+			_, err = projectPost("PUT", url, fleet, flagVerbose)
+		} else {
+			var rsp map[string]interface{}
+			url := fmt.Sprintf("/v1/projects/%s/fleets", project)
+			rsp, err = projectPost("POST", url, fleet, flagVerbose)
+			fleetUID, _ = rsp["uid"].(string)
+		}
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("fleet '%s': %s", f.Label, err))
+			continue
+		}
+		fleetUIDs[f.Label] = fleetUID
+		if len(f.EnvVars) > 0 {
+			url := fmt.Sprintf("/v1/projects/%s/fleets/%s/environment_variables", project, fleetUID)
+			_, err = projectPost("PUT", url, ProjectEnvVars{EnvironmentVariables: f.EnvVars}, flagVerbose)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("env vars of fleet '%s': %s", f.Label, err))
+			}
+		}
+		restored = append(restored, fmt.Sprintf("fleet '%s' with %d env vars", f.Label, len(f.EnvVars)))
+	}
+
+	// Routes, which are only created so that existing routes are never duplicated
+	for _, route := range snapshot.Routes {
+		label, _ := route["label"].(string)
+		if _, err2 := routeFind(appMetadata, label); err2 == nil {
+			skipped = append(skipped, fmt.Sprintf("route '%s': already exists", label))
+			continue
+		}
+		if unmapped := routeMapFleets(route, fleetUIDs); len(unmapped) > 0 {
+			skipped = append(skipped, fmt.Sprintf("fleets '%s' of route '%s': not restored, so removed from its fleet filter", strings.Join(unmapped, "', '"), label))
+		}
+		url := fmt.Sprintf("/v1/projects/%s/routes", project)
+		_, err = projectPost("POST", url, route, flagVerbose)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("route '%s': %s", label, err))
+			continue
+		}
+		restored = append(restored, fmt.Sprintf("route '%s'", label))
+	}
+
+	// Products, which are only created if they don't already exist
+	for _, product := range snapshot.Products {
+		productUID, _ := product["uid"].(string)
+		label, _ := product["label"].(string)
+		if snapshot.ProjectUID != project {
+			skipped = append(skipped, fmt.Sprintf("product '%s': %s can only be restored into %s, the project from which it was taken", label, productUID, snapshot.ProjectUID))
+			continue
+		}
+		exists := false
+		for _, p := range appMetadata.Products {
+			if strings.EqualFold(p.UID, productUID) {
+				exists = true
+			}
+		}
+		if exists {
+			skipped = append(skipped, fmt.Sprintf("product '%s': already exists", label))
+			continue
+		}
+		url := fmt.Sprintf("/v1/projects/%s/products", project)
+		_, err = projectPost("POST", url, product, flagVerbose)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("product '%s': %s", label, err))
+			continue
+		}
+		restored = append(restored, fmt.Sprintf("product '%s'", label))
+	}
+	err = nil
+
+	// Report the results
+	for _, s := range restored {
+		fmt.Printf("restored %s\n", s)
+	}
+	for _, s := range skipped {
+		fmt.Printf("SKIPPED %s\n", s)
+	}
+
+	return
+
+}
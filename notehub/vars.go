@@ -6,6 +6,10 @@ package main
 
 import (
 	"fmt"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/blues/note-cli/lib"
 	"github.com/blues/note-go/note"
@@ -14,41 +18,119 @@ import (
 
 type Vars map[string]string
 
-// Load env vars into metadata from a list of devices
-func varsGetFromDevices(appMetadata AppMetadata, uids []string, flagVerbose bool) (vars map[string]Vars, err error) {
+// Maximum number of concurrent requests when fetching env vars
+const varsConcurrency = 8
+
+// Fetch env vars for a list of UIDs using a bounded pool of concurrent requests
+func varsGetConcurrently(uids []string, fetch func(uid string) (Vars, error)) (vars map[string]Vars, err error) {
 
 	vars = map[string]Vars{}
 
-	for _, deviceUID := range uids {
-		varsRsp := notegoapi.GetDeviceEnvironmentVariablesResponse{}
-		url := fmt.Sprintf("/v1/projects/%s/devices/%s/environment_variables", appMetadata.App.UID, deviceUID)
-		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &varsRsp)
-		if err != nil {
-			return
-		}
-		vars[deviceUID] = varsRsp.EnvironmentVariables
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	pool := make(chan bool, varsConcurrency)
+	for _, uid := range uids {
+		wg.Add(1)
+		pool <- true
+		go func(uid string) {
+			defer func() {
+				<-pool
+				wg.Done()
+			}()
+			v, err2 := fetch(uid)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err2 != nil {
+				if err == nil {
+					err = fmt.Errorf("%s: %s", uid, err2)
+				}
+				return
+			}
+			vars[uid] = v
+		}(uid)
 	}
+	wg.Wait()
 
 	return
 
 }
 
+// Load env vars into metadata from a list of devices
+func varsGetFromDevices(appMetadata AppMetadata, uids []string, flagVerbose bool) (vars map[string]Vars, err error) {
+
+	return varsGetConcurrently(uids, func(deviceUID string) (Vars, error) {
+		varsRsp := notegoapi.GetDeviceEnvironmentVariablesResponse{}
+		url := fmt.Sprintf("/v1/projects/%s/devices/%s/environment_variables", appMetadata.App.UID, deviceUID)
+		err := reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &varsRsp)
+		return varsRsp.EnvironmentVariables, err
+	})
+
+}
+
 // Load env vars into metadata from a list of fleets
 func varsGetFromFleets(appMetadata AppMetadata, uids []string, flagVerbose bool) (vars map[string]Vars, err error) {
 
-	vars = map[string]Vars{}
-
-	for _, fleetUID := range uids {
+	return varsGetConcurrently(uids, func(fleetUID string) (Vars, error) {
 		varsRsp := notegoapi.GetFleetEnvironmentVariablesResponse{}
 		url := fmt.Sprintf("/v1/projects/%s/fleets/%s/environment_variables", appMetadata.App.UID, fleetUID)
-		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &varsRsp)
-		if err != nil {
-			return
+		err := reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &varsRsp)
+		return varsRsp.EnvironmentVariables, err
+	})
+
+}
+
+// Display env vars as a table with a row per variable and a column per target
+func varsShowTable(vars map[string]Vars) {
+
+	// Gather the sorted targets and the sorted union of all variable names
+	targets := []string{}
+	names := []string{}
+	for target, v := range vars {
+		targets = append(targets, target)
+		for name := range v {
+			names = append(names, name)
+		}
+	}
+	targets = sortAndRemoveDuplicates(targets)
+	names = sortAndRemoveDuplicates(names)
+
+	// Determine column widths
+	nameWidth := len("VARIABLE")
+	for _, name := range names {
+		if len(name) > nameWidth {
+			nameWidth = len(name)
 		}
-		vars[fleetUID] = varsRsp.EnvironmentVariables
+	}
+	widths := []int{}
+	for _, target := range targets {
+		w := len(target)
+		for _, name := range names {
+			if len(vars[target][name]) > w {
+				w = len(vars[target][name])
+			}
+		}
+		widths = append(widths, w)
 	}
 
-	return
+	// Display the table, using '-' where a target doesn't have the variable
+	fmt.Printf("%-*s", nameWidth, "VARIABLE")
+	for i, target := range targets {
+		fmt.Printf("  %-*s", widths[i], target)
+	}
+	fmt.Printf("\n")
+	for _, name := range names {
+		fmt.Printf("%-*s", nameWidth, name)
+		for i, target := range targets {
+			value, present := vars[target][name]
+			if !present {
+				value = "-"
+			}
+			fmt.Printf("  %-*s", widths[i], value)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("%d variables across %d targets\n", len(names), len(targets))
+
 }
 
 // Load env vars into metadata from a list of devices and set their values
@@ -140,3 +222,68 @@ func varsProvisionDevices(appMetadata AppMetadata, uids []string, productUID str
 	return
 
 }
+
+// Parse KEY=VALUE assignments into a template of vars to be set
+func varsParseAssignments(assignments []string) (template Vars, err error) {
+	template = Vars{}
+	for _, kv := range assignments {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("'%s' must be in the form KEY=VALUE", kv)
+		}
+		template[strings.TrimSpace(kv[:i])] = kv[i+1:]
+	}
+	return
+}
+
+// Delete env vars from a device or fleet, where kind is "devices" or "fleets"
+func varsDelete(appMetadata AppMetadata, kind string, uid string, keys []string, flagVerbose bool) (err error) {
+	for _, key := range keys {
+		url := fmt.Sprintf("/v1/projects/%s/%s/%s/environment_variables/%s", appMetadata.App.UID, kind, uid, neturl.PathEscape(key))
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "DELETE", url, nil, nil)
+		if err != nil {
+			return fmt.Errorf("%s: can't delete %s: %s", uid, key, err)
+		}
+	}
+	return
+}
+
+// Display the env vars of each target as sorted KEY=VALUE lines, or as JSON
+func varsShow(vars map[string]Vars, flagJson bool, flagPretty bool) (err error) {
+
+	if flagJson {
+		var varsJSON []byte
+		if flagPretty {
+			varsJSON, err = note.JSONMarshalIndent(vars, "", "    ")
+		} else {
+			varsJSON, err = note.JSONMarshal(vars)
+		}
+		if err == nil {
+			fmt.Printf("%s\n", varsJSON)
+		}
+		return
+	}
+
+	targets := []string{}
+	for target := range vars {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		names := []string{}
+		for name := range vars[target] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("%s:\n", target)
+		for _, name := range names {
+			fmt.Printf("    %s=%s\n", name, vars[target][name])
+		}
+		if len(names) == 0 {
+			fmt.Printf("    (no environment variables)\n")
+		}
+	}
+
+	return
+
+}
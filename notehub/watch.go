@@ -0,0 +1,108 @@
+// Copyright 2024 Blues Inc.  All rights reserved.
+// Use of this source code is governed by licenses granted by the
+// copyright holder including that found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blues/note-cli/lib"
+	"github.com/blues/note-go/note"
+	"github.com/fatih/color"
+)
+
+// How often to poll notehub when watching a device
+const watchIntervalSecs = 15
+
+// HealthLogEntry is a single entry of a device's health log
+type HealthLogEntry struct {
+	When  string `json:"when,omitempty"`
+	Alert bool   `json:"alert,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// HealthLogResponse is the response to a device health log query
+type HealthLogResponse struct {
+	HealthLog []HealthLogEntry `json:"health_log,omitempty"`
+}
+
+// A line of device activity to be displayed in time order
+type watchLine struct {
+	when  time.Time
+	alert bool
+	text  string
+}
+
+// Tail the events and the health log of a single device, interleaved in time order, until interrupted
+func deviceWatch(appMetadata AppMetadata, deviceUID string, flagVerbose bool) (err error) {
+
+	seenEvents := map[string]bool{}
+	seenHealth := map[string]bool{}
+	since := time.Now().UTC().Add(-1 * time.Hour)
+	fmt.Printf("watching %s (^C to stop)\n", deviceUID)
+
+	for {
+
+		lines := []watchLine{}
+
+		// New events
+		var events []Event
+		events, err = eventsGet(appMetadata, []string{deviceUID}, nil, since, flagVerbose)
+		if err != nil {
+			return
+		}
+		for _, e := range events {
+			if seenEvents[e.EventUID] {
+				continue
+			}
+			seenEvents[e.EventUID] = true
+			text := fmt.Sprintf("event  %s", e.NotefileID)
+			if e.Body != nil {
+				bodyJSON, _ := note.JSONMarshal(e.Body)
+				text += " " + string(bodyJSON)
+			}
+			lines = append(lines, watchLine{when: e.Time(), text: text})
+		}
+
+		// New health log entries
+		health := HealthLogResponse{}
+		url := fmt.Sprintf("/v1/projects/%s/devices/%s/health-log", appMetadata.App.UID, deviceUID)
+		err = reqHubV1(flagVerbose, lib.ConfigAPIHub(), "GET", url, nil, &health)
+		if err != nil {
+			return
+		}
+		for _, h := range health.HealthLog {
+			key := h.When + h.Text
+			if seenHealth[key] {
+				continue
+			}
+			seenHealth[key] = true
+			when, _ := time.Parse(time.RFC3339, h.When)
+			if when.Before(since) {
+				continue
+			}
+			lines = append(lines, watchLine{when: when, alert: h.Alert, text: "health " + h.Text})
+		}
+
+		// Display them in the order in which they happened
+		sort.SliceStable(lines, func(i, j int) bool {
+			return lines[i].when.Before(lines[j].when)
+		})
+		for _, l := range lines {
+			if l.alert {
+				color.Red("%s %s", l.when.Format(time.RFC3339), l.text)
+			} else {
+				fmt.Printf("%s %s\n", l.when.Format(time.RFC3339), l.text)
+			}
+		}
+
+		// Overlap the next query slightly so that late arrivals aren't missed
+		since = time.Now().UTC().Add(-5 * time.Minute)
+		time.Sleep(watchIntervalSecs * time.Second)
+
+	}
+
+}